@@ -1,10 +1,15 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"github.com/mis403/msgo"
+	"github.com/mis403/msgo/render"
+	"github.com/mis403/msgo/websocket"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func Log(next msgo.HandlerFunc) msgo.HandlerFunc {
@@ -15,6 +20,9 @@ func Log(next msgo.HandlerFunc) msgo.HandlerFunc {
 	}
 }
 
+//go:embed tpl/*.html
+var templates embed.FS
+
 type User struct {
 	Name    string   `xml:"name"  json:"name"`
 	Age     int      `xml:"age" json:"age" validate:"required,max=50,min=18"`
@@ -74,8 +82,8 @@ func main() {
 			log.Println(err)
 		}
 	})
-	//提前将模板加载到内存
-	engine.LoadTemplate("tpl/*.html")
+	//提前将模板加载到内存，模板嵌入到程序中，部署时不需要 tpl 目录
+	engine.LoadTemplateFS(templates, "tpl/*.html")
 	g.Get("/template", func(ctx *msgo.Context) {
 		err := ctx.Template("login.html", "")
 		if err != nil {
@@ -102,11 +110,11 @@ func main() {
 			log.Println(err)
 		}
 	})
-	g.Get("/download", func(ctx *msgo.Context) {
-		ctx.FileAttachment("tpl/text.docx", "aaa.docx")
+	g.HandleE(http.MethodGet, "/download", func(ctx *msgo.Context) error {
+		return ctx.FileAttachment("tpl/text.docx", "aaa.docx")
 	})
-	g.Get("/fs", func(ctx *msgo.Context) {
-		ctx.FileFromFS("text.docx", http.Dir("tpl"))
+	g.HandleE(http.MethodGet, "/fs", func(ctx *msgo.Context) error {
+		return ctx.FileFromFS("text.docx", http.Dir("tpl"))
 	})
 	g.Get("/toRedirect", func(ctx *msgo.Context) {
 		ctx.Redirect(http.StatusFound, "/user/hello")
@@ -131,13 +139,18 @@ func main() {
 
 	})
 	g.Post("/file", func(ctx *msgo.Context) {
-		user, _ := ctx.GetPostFormMap("user")
-		file := ctx.FormFile("file")
-		err := ctx.SaveUploadedFile(file, "./upload/"+file.Filename)
+		files, err := ctx.SaveUploadedFiles(msgo.UploadConfig{
+			Dir:          "./upload",
+			MaxFileSize:  10 << 20,
+			MaxFiles:     5,
+			AllowedTypes: []string{"image/*", "application/pdf"},
+		})
 		if err != nil {
-			log.Println(err)
+			ctx.HandleError(err)
+			return
 		}
-		ctx.JSON(http.StatusOK, user)
+		user, _ := ctx.GetPostFormMap("user")
+		ctx.JSON(http.StatusOK, map[string]any{"user": user, "files": files})
 
 	})
 	g.Post("/jsonParam", func(ctx *msgo.Context) {
@@ -152,5 +165,59 @@ func main() {
 		}
 	})
 
+	g.Get("/bindQuery", func(ctx *msgo.Context) {
+		//user[name]=张三&page=2
+		query := &struct {
+			User User `form:"user"`
+			Page int  `form:"page" default:"1"`
+		}{}
+		if err := ctx.BindQuery(query); err != nil {
+			return
+		}
+		ctx.JSON(http.StatusOK, query)
+	})
+	g.Post("/bind/:id", func(ctx *msgo.Context) {
+		user := &User{}
+		if err := ctx.Bind(user); err != nil {
+			return
+		}
+		ctx.JSON(http.StatusOK, map[string]any{"id": ctx.Param("id"), "user": user})
+	})
+	g.Get("/sse", func(ctx *msgo.Context) {
+		events := make(chan render.SSEvent)
+		//ctx 在处理函数返回后会被复用，goroutine 中不能再访问
+		done := ctx.R.Context().Done()
+		go func() {
+			defer close(events)
+			for i := 0; i < 10; i++ {
+				select {
+				case events <- render.SSEvent{Id: strconv.Itoa(i), Event: "tick", Data: time.Now().Format("2006-01-02 15:04:05")}:
+				case <-done:
+					return
+				}
+				select {
+				case <-time.After(time.Second):
+				case <-done:
+					return
+				}
+			}
+		}()
+		ctx.SSEStream(events, 15*time.Second)
+	})
+	g.WebSocket("/ws", func(ctx *msgo.Context, conn *websocket.Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}, Log)
+	g.HandleE(http.MethodGet, "/error", func(ctx *msgo.Context) error {
+		return msgo.NewHTTPError(http.StatusBadRequest, "参数错误")
+	})
+
 	engine.Run()
 }
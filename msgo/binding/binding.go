@@ -0,0 +1,79 @@
+package binding
+
+import "net/http"
+
+// 常用的 Content-Type
+const (
+	MIMEJSON              = "application/json"
+	MIMEHTML              = "text/html"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPlain             = "text/plain"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMETOML              = "application/toml"
+	MIMEMSGPACK           = "application/x-msgpack"
+	MIMEMSGPACK2          = "application/msgpack"
+	MIMEPROTOBUF          = "application/x-protobuf"
+)
+
+// Binding 从请求中解析数据到 obj，obj 一般为结构体指针
+type Binding interface {
+	Name() string
+	Bind(*http.Request, any) error
+}
+
+// BindingBody 可以直接从 body 的字节中解析，用于 body 需要读取多次的场景
+type BindingBody interface {
+	Binding
+	BindBody([]byte, any) error
+}
+
+// BindingUri 从路径参数中解析
+type BindingUri interface {
+	Name() string
+	BindUri(map[string][]string, any) error
+}
+
+var (
+	JSON                      BindingBody = jsonBinding{}
+	JSONDisallowUnknownFields BindingBody = jsonBinding{disallowUnknownFields: true}
+	XML                       BindingBody = xmlBinding{}
+	Form                      Binding     = formBinding{}
+	Query                     Binding     = queryBinding{}
+	FormPost                  Binding     = formPostBinding{}
+	FormMultipart             Binding     = formMultipartBinding{}
+	Uri                       BindingUri  = uriBinding{}
+	Header                    Binding     = headerBinding{}
+	YAML                      BindingBody = yamlBinding{}
+	TOML                      BindingBody = tomlBinding{}
+	MsgPack                   BindingBody = msgpackBinding{}
+	ProtoBuf                  BindingBody = protobufBinding{}
+)
+
+// Default 根据请求方式和 Content-Type 选择 Binding
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet {
+		return Form
+	}
+	switch contentType {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEYAML, MIMEYAML2:
+		return YAML
+	case MIMETOML:
+		return TOML
+	case MIMEMSGPACK, MIMEMSGPACK2:
+		return MsgPack
+	case MIMEPROTOBUF:
+		return ProtoBuf
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	default:
+		return Form
+	}
+}
@@ -0,0 +1,453 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errUnknownType = errors.New("unknown type")
+
+// FieldError 字段转换失败的错误，记录结构体字段名、参数 key 和原始值
+type FieldError struct {
+	Field string
+	Key   string
+	Value string
+	Type  reflect.Type
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("binding: field %s (key %q): cannot convert %q to %s: %v", e.Field, e.Key, e.Value, e.Type, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// setOptions 字段上的默认值，来自 default tag
+type setOptions struct {
+	isDefaultExists bool
+	defaultValue    string
+}
+
+// setter 从数据源中取出 key 对应的值设置到字段上
+type setter interface {
+	TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (isSet bool, err error)
+}
+
+// formSource 普通表单、query、路径参数的数据源
+type formSource map[string][]string
+
+var _ setter = formSource(nil)
+
+func (form formSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	return setByForm(value, field, form, key, opt)
+}
+
+func (form formSource) hasNested(prefix string) bool {
+	return hasNestedKey(form, prefix)
+}
+
+// hasNestedKey 判断 m 中是否有 prefix[ 开头的 key
+func hasNestedKey[V any](m map[string]V, prefix string) bool {
+	prefix += "["
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// multipartRequest multipart 表单数据源，除普通字段外还可以设置上传的文件
+type multipartRequest http.Request
+
+var _ setter = (*multipartRequest)(nil)
+
+var (
+	multipartFileHeaderStructType = reflect.TypeOf(multipart.FileHeader{})
+	multipartFileHeaderPtrType    = reflect.TypeOf(&multipart.FileHeader{})
+	timeType                      = reflect.TypeOf(time.Time{})
+	textUnmarshalerType           = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func (r *multipartRequest) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	if files := r.MultipartForm.File[key]; len(files) != 0 {
+		return setByMultipartFormFile(value, field, files)
+	}
+	return setByForm(value, field, r.MultipartForm.Value, key, opt)
+}
+
+func (r *multipartRequest) hasNested(prefix string) bool {
+	return hasNestedKey(r.MultipartForm.Value, prefix) || hasNestedKey(r.MultipartForm.File, prefix)
+}
+
+func setByMultipartFormFile(value reflect.Value, field reflect.StructField, files []*multipart.FileHeader) (bool, error) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.Type() == multipartFileHeaderPtrType {
+			value.Set(reflect.ValueOf(files[0]))
+			return true, nil
+		}
+	case reflect.Struct:
+		if value.Type() == multipartFileHeaderStructType {
+			value.Set(reflect.ValueOf(*files[0]))
+			return true, nil
+		}
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(files), len(files))
+		for i := range files {
+			if ok, err := setByMultipartFormFile(slice.Index(i), field, files[i:i+1]); !ok || err != nil {
+				return ok, err
+			}
+		}
+		value.Set(slice)
+		return true, nil
+	}
+	return false, fmt.Errorf("unsupported field type %s for file %q", value.Type(), field.Name)
+}
+
+func mapForm(ptr any, form map[string][]string) error {
+	return mappingByPtr(ptr, formSource(form), "form")
+}
+
+func mapUri(ptr any, m map[string][]string) error {
+	return mappingByPtr(ptr, formSource(m), "uri")
+}
+
+// MapForm 将 form 中的数据按照 form tag 映射到 ptr 指向的结构体
+func MapForm(ptr any, form map[string][]string) error {
+	return mapForm(ptr, form)
+}
+
+func mappingByPtr(ptr any, setter setter, tag string) error {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("binding: obj must be a non-nil pointer")
+	}
+	m := &mapper{setter: setter, tag: tag, visiting: make(map[reflect.Type]int)}
+	_, err := m.mapping(value, reflect.StructField{Anonymous: true}, "")
+	return err
+}
+
+// maxMappingDepth 嵌套结构体的最大层数，防止自引用的结构体配合很深的 key 消耗过多资源
+const maxMappingDepth = 32
+
+// nestedSource 可以判断数据源中是否有 prefix 下的嵌套 key，例如 prefix 为 parent 时的 parent[name]
+type nestedSource interface {
+	hasNested(prefix string) bool
+}
+
+type mapper struct {
+	setter setter
+	tag    string
+	//正在处理的结构体类型及其层数，用于处理自引用的结构体
+	visiting map[reflect.Type]int
+	depth    int
+}
+
+// mapping 递归遍历结构体字段，用 tag 中的名字作为 key 从数据源中取值。
+// 嵌套的结构体字段使用 user[name] 的形式作为 key，匿名嵌入的结构体和外层共用 key。
+// 自引用的结构体（例如 Parent *Node）只有数据源中存在对应前缀的 key 时才继续递归，最多 maxMappingDepth 层
+func (m *mapper) mapping(value reflect.Value, field reflect.StructField, prefix string) (bool, error) {
+	if field.Tag.Get(m.tag) == "-" {
+		return false, nil
+	}
+
+	if value.Kind() == reflect.Ptr {
+		isNew := false
+		vPtr := value
+		if value.IsNil() {
+			isNew = true
+			vPtr = reflect.New(value.Type().Elem())
+		}
+		isSet, err := m.mapping(vPtr.Elem(), field, prefix)
+		if err != nil {
+			return false, err
+		}
+		if isNew && isSet {
+			value.Set(vPtr)
+		}
+		return isSet, nil
+	}
+
+	key := prefix
+	if !field.Anonymous {
+		key = m.fieldKey(field, prefix)
+	}
+
+	if !isNestedStruct(value) || !field.Anonymous {
+		ok, err := m.tryToSetValue(value, field, key)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	if isNestedStruct(value) {
+		tValue := value.Type()
+		if m.depth >= maxMappingDepth || (m.visiting[tValue] > 0 && !m.hasNested(key)) {
+			return false, nil
+		}
+		m.visiting[tValue]++
+		m.depth++
+		defer func() {
+			m.visiting[tValue]--
+			m.depth--
+		}()
+		isSet := false
+		for i := 0; i < value.NumField(); i++ {
+			sf := tValue.Field(i)
+			//未导出的字段无法设置
+			if sf.PkgPath != "" && !sf.Anonymous {
+				continue
+			}
+			ok, err := m.mapping(value.Field(i), sf, key)
+			if err != nil {
+				return false, err
+			}
+			isSet = isSet || ok
+		}
+		return isSet, nil
+	}
+	return false, nil
+}
+
+// hasNested 数据源不支持判断时不再递归
+func (m *mapper) hasNested(prefix string) bool {
+	source, ok := m.setter.(nestedSource)
+	return ok && prefix != "" && source.hasNested(prefix)
+}
+
+// fieldKey 计算字段在数据源中的 key
+func (m *mapper) fieldKey(field reflect.StructField, prefix string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(m.tag), ",")
+	if name == "" {
+		name = field.Name
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "[" + name + "]"
+}
+
+func (m *mapper) tryToSetValue(value reflect.Value, field reflect.StructField, key string) (bool, error) {
+	var opt setOptions
+	if defaultValue, ok := field.Tag.Lookup("default"); ok {
+		opt.isDefaultExists = true
+		opt.defaultValue = defaultValue
+	}
+	return m.setter.TrySet(value, field, key, opt)
+}
+
+// isNestedStruct 需要递归处理字段的结构体，time.Time 和实现了 TextUnmarshaler 的类型作为单个值处理
+func isNestedStruct(value reflect.Value) bool {
+	if value.Kind() != reflect.Struct {
+		return false
+	}
+	t := value.Type()
+	return t != timeType && t != multipartFileHeaderStructType && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setByForm(value reflect.Value, field reflect.StructField, form map[string][]string, key string, opt setOptions) (bool, error) {
+	vs, ok := form[key]
+	if !ok || len(vs) == 0 {
+		if !opt.isDefaultExists {
+			return false, nil
+		}
+		vs = []string{opt.defaultValue}
+		if isMultiValue(value) {
+			vs = strings.Split(opt.defaultValue, ",")
+		}
+	}
+	kind := value.Kind()
+	if !isMultiValue(value) {
+		kind = reflect.Invalid
+	}
+	switch kind {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setWithProperType(s, slice.Index(i), field); err != nil {
+				return false, fieldError(field, fmt.Sprintf("%s[%d]", key, i), s, slice.Index(i), err)
+			}
+		}
+		value.Set(slice)
+	case reflect.Array:
+		if len(vs) != value.Len() {
+			return false, fieldError(field, key, strings.Join(vs, ","), value,
+				fmt.Errorf("expected %d values, got %d", value.Len(), len(vs)))
+		}
+		for i, s := range vs {
+			if err := setWithProperType(s, value.Index(i), field); err != nil {
+				return false, fieldError(field, fmt.Sprintf("%s[%d]", key, i), s, value.Index(i), err)
+			}
+		}
+	default:
+		if err := setWithProperType(vs[0], value, field); err != nil {
+			return false, fieldError(field, key, vs[0], value, err)
+		}
+	}
+	return true, nil
+}
+
+// isMultiValue 切片和数组按多个值设置，实现了 TextUnmarshaler 的类型（例如 net.IP）作为单个值处理
+func isMultiValue(value reflect.Value) bool {
+	kind := value.Kind()
+	return (kind == reflect.Slice || kind == reflect.Array) && !reflect.PointerTo(value.Type()).Implements(textUnmarshalerType)
+}
+
+func fieldError(field reflect.StructField, key, val string, value reflect.Value, err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	return &FieldError{Field: field.Name, Key: key, Value: val, Type: value.Type(), Err: err}
+}
+
+func setWithProperType(val string, value reflect.Value, field reflect.StructField) error {
+	//time.Time 也实现了 TextUnmarshaler，需要先处理以支持 time_format
+	if value.Type() == timeType {
+		return setTimeField(val, field, value)
+	}
+	if value.Kind() != reflect.Ptr && value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == reflect.TypeOf(time.Duration(0)) {
+			return setTimeDuration(val, value)
+		}
+		return setIntField(val, value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setUintField(val, value)
+	case reflect.Bool:
+		return setBoolField(val, value)
+	case reflect.Float32, reflect.Float64:
+		return setFloatField(val, value)
+	case reflect.String:
+		value.SetString(val)
+	case reflect.Ptr:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setWithProperType(val, value.Elem(), field)
+	default:
+		return errUnknownType
+	}
+	return nil
+}
+
+// setTimeField 解析时间，支持 time_format、time_utc、time_location 三个 tag，
+// time_format 为 unix、unixmilli、unixnano 时按时间戳解析
+func setTimeField(val string, field reflect.StructField, value reflect.Value) error {
+	timeFormat := field.Tag.Get("time_format")
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+	if val == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	switch tf := strings.ToLower(timeFormat); tf {
+	case "unix", "unixmilli", "unixnano":
+		tv, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		var t time.Time
+		switch tf {
+		case "unix":
+			t = time.Unix(tv, 0)
+		case "unixmilli":
+			t = time.UnixMilli(tv)
+		default:
+			t = time.Unix(0, tv)
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	l := time.Local
+	if isUTC, _ := strconv.ParseBool(field.Tag.Get("time_utc")); isUTC {
+		l = time.UTC
+	}
+	if locTag := field.Tag.Get("time_location"); locTag != "" {
+		loc, err := time.LoadLocation(locTag)
+		if err != nil {
+			return err
+		}
+		l = loc
+	}
+	t, err := time.ParseInLocation(timeFormat, val, l)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func setTimeDuration(val string, value reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	d, err := time.ParseDuration(val)
+	if err == nil {
+		value.SetInt(int64(d))
+	}
+	return err
+}
+
+func setIntField(val string, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	intVal, err := strconv.ParseInt(val, 10, field.Type().Bits())
+	if err == nil {
+		field.SetInt(intVal)
+	}
+	return err
+}
+
+func setUintField(val string, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	uintVal, err := strconv.ParseUint(val, 10, field.Type().Bits())
+	if err == nil {
+		field.SetUint(uintVal)
+	}
+	return err
+}
+
+func setBoolField(val string, field reflect.Value) error {
+	if val == "" {
+		val = "false"
+	}
+	boolVal, err := strconv.ParseBool(val)
+	if err == nil {
+		field.SetBool(boolVal)
+	}
+	return err
+}
+
+func setFloatField(val string, field reflect.Value) error {
+	if val == "" {
+		val = "0.0"
+	}
+	floatVal, err := strconv.ParseFloat(val, field.Type().Bits())
+	if err == nil {
+		field.SetFloat(floatVal)
+	}
+	return err
+}
@@ -0,0 +1,270 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type formAddress struct {
+	City string `form:"city"`
+	Zip  *int   `form:"zip"`
+}
+
+type formPage struct {
+	Page int `form:"page" default:"1"`
+	Size int `form:"size" default:"20"`
+}
+
+type formUser struct {
+	formPage
+	Name     string        `form:"name"`
+	Age      uint8         `form:"age"`
+	Score    float64       `form:"score"`
+	Admin    bool          `form:"admin"`
+	Tags     []string      `form:"tags"`
+	IDs      []int         `form:"ids" default:"1,2"`
+	Pair     [2]int        `form:"pair"`
+	Nickname *string       `form:"nickname"`
+	Timeout  time.Duration `form:"timeout"`
+	Birthday time.Time     `form:"birthday" time_format:"2006-01-02" time_utc:"true"`
+	Created  time.Time     `form:"created" time_format:"unix"`
+	IP       net.IP        `form:"ip"`
+	Address  formAddress   `form:"address"`
+	Backup   *formAddress  `form:"backup"`
+	Ignored  string        `form:"-"`
+	NoTag    string
+	secret   string
+}
+
+func TestMapForm(t *testing.T) {
+	form := url.Values{
+		"name":          {"msgo", "ignored"},
+		"age":           {"18"},
+		"score":         {"9.5"},
+		"admin":         {"true"},
+		"tags":          {"a", "b"},
+		"pair":          {"3", "4"},
+		"timeout":       {"1m30s"},
+		"birthday":      {"2000-01-02"},
+		"created":       {"1700000000"},
+		"ip":            {"127.0.0.1"},
+		"address[city]": {"Shanghai"},
+		"address[zip]":  {"200000"},
+		"size":          {"50"},
+		"Ignored":       {"x"},
+		"-":             {"x"},
+		"NoTag":         {"y"},
+		"secret":        {"z"},
+	}
+	var user formUser
+	if err := MapForm(&user, form); err != nil {
+		t.Fatal(err)
+	}
+	zip := 200000
+	want := formUser{
+		formPage: formPage{Page: 1, Size: 50},
+		Name:     "msgo",
+		Age:      18,
+		Score:    9.5,
+		Admin:    true,
+		Tags:     []string{"a", "b"},
+		IDs:      []int{1, 2},
+		Pair:     [2]int{3, 4},
+		Timeout:  90 * time.Second,
+		Birthday: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		Created:  time.Unix(1700000000, 0),
+		IP:       net.ParseIP("127.0.0.1"),
+		Address:  formAddress{City: "Shanghai", Zip: &zip},
+		NoTag:    "y",
+	}
+	if !reflect.DeepEqual(user, want) {
+		t.Fatalf("got %+v\nwant %+v", user, want)
+	}
+	//没有对应参数的指针字段保持为 nil
+	if user.Nickname != nil || user.Backup != nil {
+		t.Fatalf("unset pointers were allocated: %v %v", user.Nickname, user.Backup)
+	}
+}
+
+func TestMapFormErrors(t *testing.T) {
+	tests := []struct {
+		form  url.Values
+		field string
+		key   string
+		err   error
+	}{
+		{url.Values{"age": {"300"}}, "Age", "age", strconv.ErrRange},
+		{url.Values{"age": {"-1"}}, "Age", "age", strconv.ErrSyntax},
+		{url.Values{"score": {"abc"}}, "Score", "score", strconv.ErrSyntax},
+		{url.Values{"admin": {"yes"}}, "Admin", "admin", strconv.ErrSyntax},
+		{url.Values{"ids": {"1", "x"}}, "IDs", "ids[1]", strconv.ErrSyntax},
+		{url.Values{"address[zip]": {"1e3"}}, "Zip", "address[zip]", strconv.ErrSyntax},
+		{url.Values{"pair": {"1"}}, "Pair", "pair", nil},
+		{url.Values{"birthday": {"2000/01/02"}}, "Birthday", "birthday", nil},
+		{url.Values{"ip": {"999.0.0.1"}}, "IP", "ip", nil},
+		{url.Values{"timeout": {"10"}}, "Timeout", "timeout", nil},
+	}
+	for _, tt := range tests {
+		var user formUser
+		err := MapForm(&user, tt.form)
+		var fieldError *FieldError
+		if !errors.As(err, &fieldError) {
+			t.Errorf("%v: err = %v, want FieldError", tt.form, err)
+			continue
+		}
+		if fieldError.Field != tt.field || fieldError.Key != tt.key || (tt.err != nil && !errors.Is(err, tt.err)) {
+			t.Errorf("%v: err = %v", tt.form, err)
+		}
+	}
+
+	var user formUser
+	if err := MapForm(user, url.Values{}); err == nil {
+		t.Fatal("non-pointer should fail")
+	}
+	var unsupported struct {
+		M map[string]string `form:"m"`
+	}
+	if err := MapForm(&unsupported, url.Values{"m": {"x"}}); !errors.Is(err, errUnknownType) {
+		t.Fatalf("map field: %v", err)
+	}
+}
+
+type formNode struct {
+	Name     string      `form:"name"`
+	Next     *formNode   `form:"next"`
+	Children []*formNode `form:"children"`
+}
+
+func TestMapFormRecursiveType(t *testing.T) {
+	var node formNode
+	form := url.Values{"name": {"a"}, "next[name]": {"b"}, "next[next][name]": {"c"}}
+	if err := MapForm(&node, form); err != nil {
+		t.Fatal(err)
+	}
+	//自引用的类型按照数据源中的 key 递归，没有对应 key 的指针保持为 nil
+	if node.Name != "a" || node.Next == nil || node.Next.Name != "b" || node.Next.Next == nil || node.Next.Next.Name != "c" || node.Next.Next.Next != nil {
+		t.Fatalf("node = %+v", node)
+	}
+
+	//超过 maxMappingDepth 层的 key 被忽略，不会无限递归
+	key := "next"
+	for i := 0; i < maxMappingDepth+5; i++ {
+		key += "[next]"
+	}
+	key += "[name]"
+	node = formNode{}
+	if err := MapForm(&node, url.Values{key: {"x"}}); err != nil {
+		t.Fatal(err)
+	}
+	depth := 0
+	for n := &node; n != nil; n = n.Next {
+		depth++
+		if n.Name != "" {
+			t.Fatalf("value beyond max depth was bound at depth %d", depth)
+		}
+	}
+	if depth > maxMappingDepth {
+		t.Fatalf("depth = %d, want <= %d", depth, maxMappingDepth)
+	}
+}
+
+func TestQueryAndURIBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?name=a%20b&tags=x&tags=y&page=3", nil)
+	var user formUser
+	if err := Query.Bind(req, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "a b" || len(user.Tags) != 2 || user.Page != 3 || user.Size != 20 {
+		t.Fatalf("query = %+v", user)
+	}
+
+	var params struct {
+		ID   int64  `uri:"id"`
+		Path string `uri:"**"`
+	}
+	if err := Uri.BindUri(map[string][]string{"id": {"42"}, "**": {"a/b.png"}}, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.ID != 42 || params.Path != "a/b.png" {
+		t.Fatalf("uri = %+v", params)
+	}
+}
+
+func TestHeaderBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Add("X-Forwarded-For", "1.1.1.1")
+	req.Header.Add("X-Forwarded-For", "2.2.2.2")
+	var h struct {
+		RequestID string   `header:"x-request-id"`
+		Forwarded []string `header:"X-FORWARDED-FOR"`
+		Limit     int      `header:"X-Limit" default:"10"`
+	}
+	if err := Header.Bind(req, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.RequestID != "abc" || len(h.Forwarded) != 2 || h.Limit != 10 {
+		t.Fatalf("header = %+v", h)
+	}
+}
+
+func TestFormBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?name=query", strings.NewReader("name=body&age=20"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	var user formUser
+	if err := FormPost.Bind(req, &user); err != nil {
+		t.Fatal(err)
+	}
+	//FormPost 只使用 body 中的参数
+	if user.Name != "body" || user.Age != 20 {
+		t.Fatalf("form-urlencoded = %+v", user)
+	}
+}
+
+func TestMultipartBinding(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("name", "msgo")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, _ := mw.CreateFormFile("files", name)
+		_, _ = w.Write([]byte(name))
+	}
+	w, _ := mw.CreateFormFile("avatar", "avatar.png")
+	_, _ = w.Write([]byte("png"))
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	var form struct {
+		Name   string                  `form:"name"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Files  []*multipart.FileHeader `form:"files"`
+		Other  multipart.FileHeader    `form:"other"`
+	}
+	if err := FormMultipart.Bind(req, &form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Name != "msgo" || form.Avatar == nil || form.Avatar.Filename != "avatar.png" || len(form.Files) != 2 || form.Files[1].Filename != "b.txt" {
+		t.Fatalf("multipart = %+v", form)
+	}
+
+	var wrong struct {
+		Avatar string `form:"avatar"`
+	}
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.MultipartForm = &multipart.Form{File: map[string][]*multipart.FileHeader{"avatar": {{Filename: "a.png"}}}}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if err := FormMultipart.Bind(req, &wrong); err == nil {
+		t.Fatal("file bound to string field should fail")
+	}
+}
@@ -0,0 +1,32 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+type jsonBinding struct {
+	disallowUnknownFields bool
+}
+
+func (jsonBinding) Name() string {
+	return "json"
+}
+
+func (b jsonBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	return b.decodeJSON(req.Body, obj)
+}
+
+func (b jsonBinding) BindBody(body []byte, obj any) error {
+	return b.decodeJSON(bytes.NewReader(body), obj)
+}
+
+// decodeJSON 解析的同时检查 must:"required" 的字段
+func (b jsonBinding) decodeJSON(r io.Reader, obj any) error {
+	return DecodeJSON(r, obj, b.disallowUnknownFields)
+}
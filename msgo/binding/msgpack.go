@@ -0,0 +1,30 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"net/http"
+)
+
+type msgpackBinding struct{}
+
+func (msgpackBinding) Name() string {
+	return "msgpack"
+}
+
+func (msgpackBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	return decodeMsgPack(req.Body, obj)
+}
+
+func (msgpackBinding) BindBody(body []byte, obj any) error {
+	return decodeMsgPack(bytes.NewReader(body), obj)
+}
+
+func decodeMsgPack(r io.Reader, obj any) error {
+	return msgpack.NewDecoder(r).Decode(obj)
+}
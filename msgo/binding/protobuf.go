@@ -0,0 +1,33 @@
+package binding
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+type protobufBinding struct{}
+
+func (protobufBinding) Name() string {
+	return "protobuf"
+}
+
+func (b protobufBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(buf, obj)
+}
+
+func (protobufBinding) BindBody(body []byte, obj any) error {
+	message, ok := obj.(proto.Message)
+	if !ok {
+		return errors.New("binding: obj is not a proto.Message")
+	}
+	return proto.Unmarshal(body, message)
+}
@@ -0,0 +1,451 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mis403/msgo/codec"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MissingFieldsError 标记了 must:"required" 的字段在 JSON 中不存在或者为 null，
+// 记录所有缺失字段的 JSON 路径，例如 user.address[1].city
+type MissingFieldsError []string
+
+func (e MissingFieldsError) Error() string {
+	return fmt.Sprintf("required fields [%s] are not exist", strings.Join(e, ", "))
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// DecodeJSON 从 r 中解析 JSON 到 obj，同时检查 must:"required" 的字段是否存在，只读取和解析一次 body。
+// 嵌套的结构体、指针、结构体切片都会递归检查，父级字段不存在时不再检查其子字段。
+// 没有 must:"required" 字段的类型直接使用 codec.JSON 解析；
+// 有的话结构体、切片这一层由 encoding/json 按 token 读取并记录出现的字段，其余的值交给 codec.JSON 解析
+func DecodeJSON(r io.Reader, obj any, disallowUnknownFields bool) error {
+	t := reflect.TypeOf(obj)
+	if t == nil || !hasRequiredFields(t) {
+		decoder := codec.JSON.NewDecoder(r)
+		if disallowUnknownFields {
+			//有未知的字段报错
+			decoder.DisallowUnknownFields()
+		}
+		return decoder.Decode(obj)
+	}
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return &json.InvalidUnmarshalError{Type: t}
+	}
+	d := &requiredDecoder{
+		decoder:               json.NewDecoder(r),
+		disallowUnknownFields: disallowUnknownFields,
+	}
+	if _, err := d.value(v.Elem(), "", false); err != nil {
+		return err
+	}
+	if len(d.missing) > 0 {
+		return d.missing
+	}
+	return nil
+}
+
+// requiredField 结构体中参与 JSON 解析的字段
+type requiredField struct {
+	name     string
+	index    []int
+	required bool
+	//json 标签带有 string 选项，值为字符串形式
+	quoted bool
+	typ    reflect.Type
+	//名字来自 json 标签
+	tagged bool
+}
+
+var requiredFieldsCache sync.Map // map[reflect.Type][]requiredField
+
+// cachedRequiredFields 按照 encoding/json 的规则取出结构体的 JSON 字段，匿名嵌入的结构体字段会被展开。
+// 同名的字段中层级最浅的生效，层级相同时带 json 标签的生效，仍然无法区分的字段都被忽略
+func cachedRequiredFields(t reflect.Type) []requiredField {
+	if f, ok := requiredFieldsCache.Load(t); ok {
+		return f.([]requiredField)
+	}
+	all := collectFields(t, nil, map[reflect.Type]bool{})
+	byName := make(map[string][]requiredField, len(all))
+	for _, f := range all {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	fields := make([]requiredField, 0, len(all))
+	for _, f := range all {
+		if dominant, ok := dominantField(byName[f.name]); ok && sameIndex(dominant.index, f.index) {
+			fields = append(fields, f)
+		}
+	}
+	requiredFieldsCache.Store(t, fields)
+	return fields
+}
+
+// collectFields 展开匿名嵌入的结构体，返回所有层级的字段，visiting 防止嵌入自身的指针时无限递归
+func collectFields(t reflect.Type, index []int, visiting map[reflect.Type]bool) []requiredField {
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	var fields []requiredField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, collectFields(ft, fieldIndex, visiting)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = sf.Name
+		}
+		fields = append(fields, requiredField{
+			name:     name,
+			index:    fieldIndex,
+			required: sf.Tag.Get("must") == "required",
+			quoted:   hasOption(opts, "string") && isQuotable(sf.Type),
+			typ:      sf.Type,
+			tagged:   tagged,
+		})
+	}
+	return fields
+}
+
+// dominantField 和 encoding/json 一样从同名的字段中选出生效的字段
+func dominantField(fields []requiredField) (requiredField, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields[1:] {
+		if len(f.index) < depth {
+			depth = len(f.index)
+		}
+	}
+	var dominant []requiredField
+	for _, f := range fields {
+		if len(f.index) == depth {
+			dominant = append(dominant, f)
+		}
+	}
+	if len(dominant) > 1 {
+		tagged := dominant[:0:0]
+		for _, f := range dominant {
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		dominant = tagged
+	}
+	if len(dominant) != 1 {
+		return requiredField{}, false
+	}
+	return dominant[0], true
+}
+
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuotable 和 encoding/json 一样，string 选项只对布尔、数字和字符串类型（包括指针）生效
+func isQuotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+var hasRequiredCache sync.Map // map[reflect.Type]bool
+
+// hasRequiredFields 判断类型中是否有 must:"required" 的字段，没有的话不需要逐个 token 读取
+func hasRequiredFields(t reflect.Type) bool {
+	if has, ok := hasRequiredCache.Load(t); ok {
+		return has.(bool)
+	}
+	has := hasRequired(t, make(map[reflect.Type]bool))
+	hasRequiredCache.Store(t, has)
+	return has
+}
+
+// hasRequired visited 防止自引用的结构体无限递归
+func hasRequired(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return hasRequired(t.Elem(), visited)
+	case reflect.Struct:
+		for _, f := range cachedRequiredFields(t) {
+			if f.required || hasRequired(f.typ, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type requiredDecoder struct {
+	decoder               *json.Decoder
+	disallowUnknownFields bool
+	missing               MissingFieldsError
+}
+
+// value 读取一个 JSON 值解析到 v，返回值是否存在（不为 null）
+func (d *requiredDecoder) value(v reflect.Value, path string, quoted bool) (bool, error) {
+	if !hasRequiredFields(v.Type()) {
+		return d.leaf(v, path, quoted)
+	}
+	tok, err := d.decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		//和 encoding/json 一样，null 将指针和切片置为 nil，其他类型保持不变
+		if v.Kind() == reflect.Ptr || v.Kind() == reflect.Slice {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return false, nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if tok != json.Delim('{') {
+			return false, d.typeError(tok, v.Type(), path)
+		}
+		return true, d.object(v, path)
+	case reflect.Slice, reflect.Array:
+		if tok != json.Delim('[') {
+			return false, d.typeError(tok, v.Type(), path)
+		}
+		return true, d.array(v, path)
+	}
+	return false, d.typeError(tok, v.Type(), path)
+}
+
+// leaf 不需要检查的值交给 codec.JSON 解析
+func (d *requiredDecoder) leaf(v reflect.Value, path string, quoted bool) (bool, error) {
+	var raw json.RawMessage
+	if err := d.decoder.Decode(&raw); err != nil {
+		return false, err
+	}
+	if string(raw) == "null" {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return false, nil
+	}
+	if quoted {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return false, fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %s into %v (field %s)", raw, v.Type(), path)
+		}
+		raw = json.RawMessage(s)
+	}
+	decoder := codec.JSON.NewDecoder(bytes.NewReader(raw))
+	if d.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v.Addr().Interface()); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) && path != "" {
+			//错误中的字段路径是相对于当前值的，补全为完整路径
+			if typeError.Field == "" {
+				typeError.Field = path
+			} else {
+				typeError.Field = joinPath(path, typeError.Field)
+			}
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *requiredDecoder) object(v reflect.Value, path string) error {
+	fields := cachedRequiredFields(v.Type())
+	seen := make([]bool, len(fields))
+	for d.decoder.More() {
+		tok, err := d.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		index := matchField(fields, key)
+		if index < 0 {
+			if d.disallowUnknownFields {
+				return fmt.Errorf("json: unknown field %q", key)
+			}
+			var raw json.RawMessage
+			if err := d.decoder.Decode(&raw); err != nil {
+				return err
+			}
+			continue
+		}
+		f := fields[index]
+		fv, err := fieldByIndex(v, f.index)
+		if err != nil {
+			return err
+		}
+		present, err := d.value(fv, joinPath(path, f.name), f.quoted)
+		if err != nil {
+			return err
+		}
+		seen[index] = present
+	}
+	//读取结尾的 }
+	if _, err := d.decoder.Token(); err != nil {
+		return err
+	}
+	for i, f := range fields {
+		if f.required && !seen[i] {
+			d.missing = append(d.missing, joinPath(path, f.name))
+		}
+	}
+	return nil
+}
+
+// array 和 encoding/json 一样，切片从长度 0 开始追加，数组多出的元素丢弃，不足的部分置为零值
+func (d *requiredDecoder) array(v reflect.Value, path string) error {
+	isSlice := v.Kind() == reflect.Slice
+	if isSlice {
+		v.SetLen(0)
+	}
+	i := 0
+	for ; d.decoder.More(); i++ {
+		if isSlice {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		if i >= v.Len() {
+			var raw json.RawMessage
+			if err := d.decoder.Decode(&raw); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := d.value(v.Index(i), path+"["+strconv.Itoa(i)+"]", false); err != nil {
+			return err
+		}
+	}
+	for ; !isSlice && i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	if isSlice && v.IsNil() {
+		//[] 解析为空切片而不是 nil
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	//读取结尾的 ]
+	_, err := d.decoder.Token()
+	return err
+}
+
+// fieldByIndex 取出嵌套的字段，匿名嵌入的结构体指针为 nil 时分配
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func (d *requiredDecoder) typeError(tok json.Token, t reflect.Type, path string) error {
+	value := "number"
+	switch tok.(type) {
+	case bool:
+		value = "bool"
+	case string:
+		value = "string"
+	case json.Delim:
+		if tok == json.Delim('[') {
+			value = "array"
+		} else {
+			value = "object"
+		}
+	}
+	return &json.UnmarshalTypeError{Value: value, Type: t, Offset: d.decoder.InputOffset(), Field: path}
+}
+
+// matchField 和 encoding/json 一样，优先精确匹配，其次忽略大小写匹配
+func matchField(fields []requiredField, key string) int {
+	for i, f := range fields {
+		if f.name == key {
+			return i
+		}
+	}
+	for i, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,212 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type requiredAddress struct {
+	City   string `json:"city" must:"required"`
+	Street string `json:"street,omitempty"`
+}
+
+type requiredBase struct {
+	ID int64 `json:"id,string" must:"required"`
+}
+
+type requiredUser struct {
+	requiredBase
+	Name      string            `json:"name,omitempty" must:"required"`
+	Age       *int              `json:"age" must:"required"`
+	Address   *requiredAddress  `json:"address"`
+	Addresses []requiredAddress `json:"addresses"`
+	Tags      map[string]string `json:"tags"`
+	Created   time.Time         `json:"created"`
+	Ignored   string            `json:"-" must:"required"`
+}
+
+func decodeRequired(body string, obj any, disallowUnknownFields bool) error {
+	return DecodeJSON(strings.NewReader(body), obj, disallowUnknownFields)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var user requiredUser
+	body := `{"id":"7","NAME":"msgo","age":18,"address":{"city":"a"},"addresses":[{"city":"b","street":"c"}],` +
+		`"tags":{"k":"v"},"created":"2024-01-02T03:04:05Z","unknown":[1,{"x":null}]}`
+	if err := decodeRequired(body, &user, false); err != nil {
+		t.Fatal(err)
+	}
+	age := 18
+	want := requiredUser{
+		requiredBase: requiredBase{ID: 7},
+		Name:         "msgo",
+		Age:          &age,
+		Address:      &requiredAddress{City: "a"},
+		Addresses:    []requiredAddress{{City: "b", Street: "c"}},
+		Tags:         map[string]string{"k": "v"},
+		Created:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(user, want) {
+		t.Fatalf("got %+v, want %+v", user, want)
+	}
+}
+
+func TestDecodeJSONMissingFields(t *testing.T) {
+	tests := []struct {
+		body    string
+		missing MissingFieldsError
+	}{
+		{`{}`, MissingFieldsError{"id", "name", "age"}},
+		{`{"id":"1","name":"a","age":null}`, MissingFieldsError{"age"}},
+		{`{"id":"1","name":"a","age":1,"address":{}}`, MissingFieldsError{"address.city"}},
+		//父级字段不存在时不检查子字段
+		{`{"id":"1","name":"a","age":1,"address":null}`, nil},
+		{`{"id":"1","name":"a","age":1,"addresses":[{"city":"a"},{"street":"b"},{"city":null}]}`, MissingFieldsError{"addresses[1].city", "addresses[2].city"}},
+	}
+	for _, tt := range tests {
+		var user requiredUser
+		err := decodeRequired(tt.body, &user, false)
+		var missing MissingFieldsError
+		errors.As(err, &missing)
+		if !reflect.DeepEqual(missing, tt.missing) || (err == nil) != (tt.missing == nil) {
+			t.Errorf("%s: err = %v, want %v", tt.body, err, tt.missing)
+		}
+	}
+}
+
+func TestDecodeJSONSlice(t *testing.T) {
+	var addresses []*requiredAddress
+	err := decodeRequired(`[{"city":"a"},null,{}]`, &addresses, false)
+	if !reflect.DeepEqual(err, MissingFieldsError{"[2].city"}) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(addresses) != 3 || addresses[0].City != "a" || addresses[1] != nil {
+		t.Fatalf("addresses = %v", addresses)
+	}
+
+	var array [2]requiredAddress
+	array[1].City = "old"
+	if err := decodeRequired(`[{"city":"a"}]`, &array, false); err != nil || array[0].City != "a" || array[1].City != "" {
+		t.Fatalf("array = %v, err = %v", array, err)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	var user requiredUser
+	err := decodeRequired(`{"id":"1","name":"a","age":1,"other":1}`, &user, true)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "other"`) {
+		t.Fatalf("unknown field: %v", err)
+	}
+	err = decodeRequired(`{"address":{"city":"a","zip":1}}`, &user, true)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "zip"`) {
+		t.Fatalf("nested unknown field: %v", err)
+	}
+
+	var typeError *json.UnmarshalTypeError
+	err = decodeRequired(`{"addresses":[{"city":1}]}`, &user, false)
+	if !errors.As(err, &typeError) || typeError.Field != "addresses[0].city" {
+		t.Fatalf("type error: %v", err)
+	}
+	err = decodeRequired(`{"address":"a"}`, &user, false)
+	if !errors.As(err, &typeError) || typeError.Field != "address" {
+		t.Fatalf("type error: %v", err)
+	}
+	if err := decodeRequired(`{"id":1}`, &user, false); err == nil || !strings.Contains(err.Error(), ",string") {
+		t.Fatalf("unquoted string option: %v", err)
+	}
+
+	var syntaxError *json.SyntaxError
+	if err := decodeRequired(`{"name":"a",}`, &user, false); !errors.As(err, &syntaxError) {
+		t.Fatalf("syntax error: %v", err)
+	}
+	if err := decodeRequired(`{"name":"a"`, &user, false); err == nil {
+		t.Fatal("truncated body should fail")
+	}
+	if err := decodeRequired(`{}`, user, false); err == nil {
+		t.Fatal("non-pointer should fail")
+	}
+}
+
+type requiredInner struct {
+	Name  string `json:"name"`
+	Title string
+	Extra string
+}
+
+type requiredOther struct {
+	Title string
+	Extra string `json:"Extra"`
+}
+
+type requiredShadow struct {
+	requiredInner
+	requiredOther
+	Name string `json:"name" must:"required"`
+}
+
+func TestDecodeJSONFieldDominance(t *testing.T) {
+	//外层的字段覆盖嵌入结构体中的同名字段
+	var v requiredShadow
+	if err := decodeRequired(`{"name":"x"}`, &v, false); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "x" || v.requiredInner.Name != "" {
+		t.Fatalf("got %+v", v)
+	}
+
+	//同一层级中带标签的字段优先，无法区分的字段被忽略，和 encoding/json 的结果一致
+	body := `{"name":"x","Title":"t","Extra":"e"}`
+	var got, want requiredShadow
+	if err := decodeRequired(body, &got, true); err == nil || !strings.Contains(err.Error(), `unknown field "Title"`) {
+		t.Fatalf("ambiguous field: %v", err)
+	}
+	got = requiredShadow{}
+	if err := decodeRequired(body, &got, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(body), &want); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if got.requiredOther.Extra != "e" || got.requiredInner.Extra != "" || got.requiredOther.Title != "" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+// onceReader 只允许读取到一次 EOF，用于确认 body 只读取一遍
+type onceReader struct {
+	r   *strings.Reader
+	eof bool
+}
+
+func (o *onceReader) Read(p []byte) (int, error) {
+	if o.eof {
+		return 0, errors.New("body read again")
+	}
+	n, err := o.r.Read(p)
+	if err != nil {
+		o.eof = true
+	}
+	return n, err
+}
+
+func TestJSONBindingRequired(t *testing.T) {
+	body := &onceReader{r: strings.NewReader(`{"id":"1","name":"a"}`)}
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	var user requiredUser
+	err := JSON.Bind(req, &user)
+	if !reflect.DeepEqual(err, MissingFieldsError{"age"}) || user.Name != "a" {
+		t.Fatalf("err = %v, user = %+v", err, user)
+	}
+	if err := JSON.BindBody([]byte(`{"name":"a"}`), &struct{ Name string }{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"github.com/pelletier/go-toml/v2"
+	"io"
+	"net/http"
+)
+
+type tomlBinding struct{}
+
+func (tomlBinding) Name() string {
+	return "toml"
+}
+
+func (tomlBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	return decodeTOML(req.Body, obj)
+}
+
+func (tomlBinding) BindBody(body []byte, obj any) error {
+	return decodeTOML(bytes.NewReader(body), obj)
+}
+
+func decodeTOML(r io.Reader, obj any) error {
+	return toml.NewDecoder(r).Decode(obj)
+}
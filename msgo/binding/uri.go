@@ -0,0 +1,11 @@
+package binding
+
+type uriBinding struct{}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+func (uriBinding) BindUri(m map[string][]string, obj any) error {
+	return mapUri(obj, m)
+}
@@ -0,0 +1,306 @@
+package msgo
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPageCacheEntries = 1000
+	defaultPageCacheBytes   = 64 << 20
+)
+
+// PageCacheConfig 页面缓存的配置
+type PageCacheConfig struct {
+	// TTL 缓存的有效期，默认为 1 分钟
+	TTL time.Duration
+	// MaxEntries 最多缓存的页面数，超过时淘汰最久没有访问的页面，默认为 1000，小于 0 表示不限制
+	MaxEntries int
+	// MaxBytes 所有缓存的 body 的总字节数上限，默认为 64MB，小于 0 表示不限制。
+	// body 超过这个大小的响应不会被缓存
+	MaxBytes int64
+	// Headers 参与缓存 key 计算的请求头，例如 Accept-Language。
+	// 带有 Authorization 或 Cookie 的请求默认不使用缓存，需要按用户缓存时把对应的请求头加入 Headers
+	Headers []string
+	// Skip 返回 true 时不使用缓存
+	Skip func(ctx *Context) bool
+}
+
+// PageCache 缓存 GET 请求的完整响应（状态码、响应头和 body），命中时直接返回，不再执行处理函数。
+// 请求携带 Cache-Control: no-cache 时跳过缓存重新生成，no-store 时既不读也不写缓存；
+// 请求带有 Authorization 或 Cookie 并且没有加入 Headers 时不使用缓存，防止不同用户的页面互相泄露；
+// 响应的状态码不是 200、带有 Set-Cookie 或者 Cache-Control 为 no-store、private 时不缓存
+type PageCache struct {
+	config    PageCacheConfig
+	mu        sync.Mutex
+	ll        *list.List
+	items     map[string]*list.Element
+	bytes     int64
+	lastSweep time.Time
+}
+
+type pageCacheEntry struct {
+	key      string
+	status   int
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+// NewPageCache 创建页面缓存，通过 Middleware 作为中间件使用
+func NewPageCache(config PageCacheConfig) *PageCache {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	//key 中包含客户端可以随意构造的 query 参数，默认需要有上限
+	if config.MaxEntries == 0 {
+		config.MaxEntries = defaultPageCacheEntries
+	}
+	if config.MaxBytes == 0 {
+		config.MaxBytes = defaultPageCacheBytes
+	}
+	return &PageCache{
+		config:    config,
+		ll:        list.New(),
+		items:     make(map[string]*list.Element),
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware 页面缓存中间件
+func (p *PageCache) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if ctx.R.Method != http.MethodGet || p.hasCredentials(ctx.R) || (p.config.Skip != nil && p.config.Skip(ctx)) {
+				next(ctx)
+				return
+			}
+			cacheControl := strings.ToLower(ctx.R.Header.Get("Cache-Control"))
+			noStore := strings.Contains(cacheControl, "no-store")
+			noCache := noStore || strings.Contains(cacheControl, "no-cache") ||
+				strings.Contains(strings.ToLower(ctx.R.Header.Get("Pragma")), "no-cache")
+			key := p.key(ctx.R)
+			if !noCache {
+				if entry := p.get(key); entry != nil {
+					p.serve(ctx, entry)
+					return
+				}
+			}
+			if noStore {
+				next(ctx)
+				return
+			}
+
+			w := &cacheWriter{ResponseWriter: ctx.W, cacheable: true, limit: p.config.MaxBytes}
+			ctx.W = w
+			defer func() {
+				ctx.W = w.ResponseWriter
+			}()
+			ctx.W.Header().Set("X-Cache", "MISS")
+			next(ctx)
+			status := ctx.writermem.Status()
+			if !w.cacheable || status != http.StatusOK || !cacheableResponse(w.Header()) {
+				return
+			}
+			header := w.Header().Clone()
+			header.Del("X-Cache")
+			p.set(&pageCacheEntry{
+				key:      key,
+				status:   status,
+				header:   header,
+				body:     w.body.Bytes(),
+				storedAt: time.Now(),
+			})
+		}
+	}
+}
+
+// Invalidate 删除 key 以 prefix 开头的缓存，key 以请求路径开头，例如 Invalidate("/blog/") 会删除所有文章页面。
+// 返回删除的数量
+func (p *PageCache) Invalidate(prefix string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for key, e := range p.items {
+		if strings.HasPrefix(key, prefix) {
+			p.removeElement(e)
+			count++
+		}
+	}
+	return count
+}
+
+// Purge 清空所有缓存
+func (p *PageCache) Purge() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ll.Init()
+	p.items = make(map[string]*list.Element)
+	p.bytes = 0
+}
+
+// Len 当前缓存的页面数
+func (p *PageCache) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ll.Len()
+}
+
+// key 由路径、排序后的 query 参数和 Headers 中的请求头组成
+// hasCredentials 请求带有身份信息，并且身份信息没有参与缓存 key 的计算
+func (p *PageCache) hasCredentials(r *http.Request) bool {
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if r.Header.Get(name) != "" && !p.keyHeader(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PageCache) keyHeader(name string) bool {
+	for _, header := range p.config.Headers {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PageCache) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	if query := r.URL.Query().Encode(); query != "" {
+		b.WriteString("?")
+		b.WriteString(query)
+	}
+	for _, name := range p.config.Headers {
+		b.WriteString("\n")
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+func (p *PageCache) get(key string) *pageCacheEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.items[key]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*pageCacheEntry)
+	if time.Since(entry.storedAt) > p.config.TTL {
+		p.removeElement(e)
+		return nil
+	}
+	p.ll.MoveToFront(e)
+	return entry
+}
+
+func (p *PageCache) set(entry *pageCacheEntry) {
+	size := int64(len(entry.body))
+	if p.config.MaxBytes > 0 && size > p.config.MaxBytes {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sweep()
+	if e, ok := p.items[entry.key]; ok {
+		p.removeElement(e)
+	}
+	p.items[entry.key] = p.ll.PushFront(entry)
+	p.bytes += size
+	for (p.config.MaxEntries > 0 && p.ll.Len() > p.config.MaxEntries) ||
+		(p.config.MaxBytes > 0 && p.bytes > p.config.MaxBytes) {
+		p.removeElement(p.ll.Back())
+	}
+}
+
+// sweep 每隔一个 TTL 删除所有过期的缓存，不再被访问的页面不会一直占用内存
+func (p *PageCache) sweep() {
+	if time.Since(p.lastSweep) < p.config.TTL {
+		return
+	}
+	p.lastSweep = time.Now()
+	for e := p.ll.Back(); e != nil; {
+		prev := e.Prev()
+		if time.Since(e.Value.(*pageCacheEntry).storedAt) > p.config.TTL {
+			p.removeElement(e)
+		}
+		e = prev
+	}
+}
+
+func (p *PageCache) removeElement(e *list.Element) {
+	entry := p.ll.Remove(e).(*pageCacheEntry)
+	delete(p.items, entry.key)
+	p.bytes -= int64(len(entry.body))
+}
+
+func (p *PageCache) serve(ctx *Context, entry *pageCacheEntry) {
+	header := ctx.W.Header()
+	for k, v := range entry.header {
+		header[k] = v
+	}
+	header.Set("X-Cache", "HIT")
+	header.Set("Age", strconv.Itoa(int(time.Since(entry.storedAt).Seconds())))
+	ctx.W.WriteHeader(entry.status)
+	_, _ = ctx.W.Write(entry.body)
+}
+
+// cacheableResponse 处理函数可以通过响应头禁止缓存
+func cacheableResponse(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	cacheControl := strings.ToLower(header.Get("Cache-Control"))
+	return !strings.Contains(cacheControl, "no-store") && !strings.Contains(cacheControl, "private")
+}
+
+// cacheWriter 写出响应的同时记录 body，流式输出、连接被接管或者 body 超过 limit 时不缓存
+type cacheWriter struct {
+	http.ResponseWriter
+	body      bytes.Buffer
+	cacheable bool
+	limit     int64
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	if w.cacheable {
+		if w.limit > 0 && int64(w.body.Len()+len(data)) > w.limit {
+			w.cacheable = false
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheWriter) Written() bool {
+	return responseWritten(w.ResponseWriter)
+}
+
+func (w *cacheWriter) Flush() {
+	w.cacheable = false
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.cacheable = false
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
@@ -0,0 +1,169 @@
+package msgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPageCacheTest(config PageCacheConfig, body func(ctx *Context) string) (*Engine, *PageCache, *int) {
+	cache := NewPageCache(config)
+	calls := 0
+	engine := NewEngine()
+	engine.Group("t").Get("/page", func(ctx *Context) {
+		calls++
+		_ = ctx.String(http.StatusOK, "%s", body(ctx))
+	}, cache.Middleware())
+	return engine, cache, &calls
+}
+
+func getPage(engine *Engine, target string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestPageCache(t *testing.T) {
+	engine, cache, calls := newPageCacheTest(PageCacheConfig{}, func(ctx *Context) string {
+		return "page " + ctx.GetQuery("p")
+	})
+	if w := getPage(engine, "/t/page?p=1"); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != "page 1" {
+		t.Fatalf("first request: %v %q", w.Header(), w.Body.String())
+	}
+	if w := getPage(engine, "/t/page?p=1"); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "page 1" {
+		t.Fatalf("second request: %v %q", w.Header(), w.Body.String())
+	}
+	if w := getPage(engine, "/t/page?p=2"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("different query should not hit the cache")
+	}
+	getPage(engine, "/t/page?p=1", "Cache-Control", "no-cache")
+	if *calls != 3 {
+		t.Fatalf("handler called %d times, want 3", *calls)
+	}
+	if n := cache.Invalidate("/t/page"); n != 2 || cache.Len() != 0 {
+		t.Fatalf("Invalidate removed %d, Len %d", n, cache.Len())
+	}
+}
+
+func TestPageCacheDefaultLimits(t *testing.T) {
+	cache := NewPageCache(PageCacheConfig{})
+	if cache.config.MaxEntries != defaultPageCacheEntries || cache.config.MaxBytes != defaultPageCacheBytes {
+		t.Fatalf("defaults = %d entries, %d bytes", cache.config.MaxEntries, cache.config.MaxBytes)
+	}
+	unlimited := NewPageCache(PageCacheConfig{MaxEntries: -1, MaxBytes: -1})
+	if unlimited.config.MaxEntries > 0 || unlimited.config.MaxBytes > 0 {
+		t.Fatal("negative limits should mean unlimited")
+	}
+}
+
+func TestPageCacheMaxEntries(t *testing.T) {
+	engine, cache, _ := newPageCacheTest(PageCacheConfig{MaxEntries: 2}, func(ctx *Context) string {
+		return ctx.GetQuery("p")
+	})
+	for _, p := range []string{"1", "2", "3", "4"} {
+		getPage(engine, "/t/page?p="+p)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", cache.Len())
+	}
+	if w := getPage(engine, "/t/page?p=1"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("least recently used page should be evicted")
+	}
+}
+
+func TestPageCacheMaxBytes(t *testing.T) {
+	engine, cache, _ := newPageCacheTest(PageCacheConfig{MaxBytes: 10}, func(ctx *Context) string {
+		return strings.Repeat("a", len(ctx.GetQuery("p")))
+	})
+	w := getPage(engine, "/t/page?p=xxxxxxxxxxxx")
+	if w.Body.Len() != 12 {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	if cache.Len() != 0 {
+		t.Fatal("response larger than MaxBytes should not be cached")
+	}
+	getPage(engine, "/t/page?p=xxxxxx")
+	getPage(engine, "/t/page?p=yyyyyy")
+	if cache.Len() != 1 || cache.bytes != 6 {
+		t.Fatalf("Len = %d, bytes = %d", cache.Len(), cache.bytes)
+	}
+}
+
+func TestPageCacheSweep(t *testing.T) {
+	engine, cache, _ := newPageCacheTest(PageCacheConfig{TTL: 20 * time.Millisecond}, func(ctx *Context) string {
+		return ctx.GetQuery("p")
+	})
+	for _, p := range []string{"1", "2", "3"} {
+		getPage(engine, "/t/page?p="+p)
+	}
+	time.Sleep(30 * time.Millisecond)
+	getPage(engine, "/t/page?p=4")
+	if cache.Len() != 1 {
+		t.Fatalf("Len = %d, expired entries should be removed", cache.Len())
+	}
+}
+
+func TestPageCacheUncacheableResponses(t *testing.T) {
+	cache := NewPageCache(PageCacheConfig{})
+	engine := NewEngine()
+	g := engine.Group("t")
+	g.Get("/cookie", func(ctx *Context) {
+		http.SetCookie(ctx.W, &http.Cookie{Name: "a", Value: "b"})
+		_ = ctx.String(http.StatusOK, "x")
+	}, cache.Middleware())
+	g.Get("/private", func(ctx *Context) {
+		ctx.W.Header().Set("Cache-Control", "private")
+		_ = ctx.String(http.StatusOK, "x")
+	}, cache.Middleware())
+	g.Get("/missing", func(ctx *Context) {
+		_ = ctx.String(http.StatusNotFound, "x")
+	}, cache.Middleware())
+	for _, path := range []string{"/t/cookie", "/t/private", "/t/missing"} {
+		getPage(engine, path)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("Len = %d, want 0", cache.Len())
+	}
+}
+
+func TestPageCacheCredentials(t *testing.T) {
+	engine, _, calls := newPageCacheTest(PageCacheConfig{}, func(ctx *Context) string {
+		return "user " + ctx.R.Header.Get("Authorization") + ctx.R.Header.Get("Cookie")
+	})
+	getPage(engine, "/t/page")
+	//带有身份信息的请求不读取也不写入缓存
+	if w := getPage(engine, "/t/page", "Authorization", "Bearer a"); w.Body.String() != "user Bearer a" || w.Header().Get("X-Cache") != "" {
+		t.Fatalf("authorized request: %v %q", w.Header(), w.Body.String())
+	}
+	if w := getPage(engine, "/t/page", "Cookie", "session=b"); w.Body.String() != "user session=b" {
+		t.Fatalf("request with cookie: %q", w.Body.String())
+	}
+	if w := getPage(engine, "/t/page"); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "user " {
+		t.Fatalf("anonymous request: %v %q", w.Header(), w.Body.String())
+	}
+	if *calls != 3 {
+		t.Fatalf("handler called %d times, want 3", *calls)
+	}
+
+	//身份信息参与 key 的计算时按用户缓存
+	engine, _, calls = newPageCacheTest(PageCacheConfig{Headers: []string{"authorization"}}, func(ctx *Context) string {
+		return "user " + ctx.R.Header.Get("Authorization")
+	})
+	for _, user := range []string{"a", "b", "a", "b"} {
+		if w := getPage(engine, "/t/page", "Authorization", user); w.Body.String() != "user "+user {
+			t.Fatalf("user %s: %q", user, w.Body.String())
+		}
+	}
+	if *calls != 2 {
+		t.Fatalf("handler called %d times, want 2", *calls)
+	}
+	if w := getPage(engine, "/t/page", "Authorization", "a", "Cookie", "c"); w.Header().Get("X-Cache") != "" {
+		t.Fatal("cookie is not part of the key and should bypass the cache")
+	}
+}
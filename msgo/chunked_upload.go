@@ -0,0 +1,474 @@
+package msgo
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statusChecksumMismatch 分片的校验和不一致，和 tus 协议相同
+const statusChecksumMismatch = 460
+
+// uploadLockStripes 上传的锁按照 id 的哈希分成固定数量的分段，不会随请求中的 id 增长
+const uploadLockStripes = 64
+
+// ChunkedUploadConfig 分片上传的配置
+type ChunkedUploadConfig struct {
+	// Dir 保存上传状态和未完成文件的目录，不存在时自动创建
+	Dir string
+	// MaxSize 单个文件的最大字节数，0 表示不限制
+	MaxSize int64
+	// MaxChunkSize 单个分片的最大字节数，0 表示不限制
+	MaxChunkSize int64
+	// Expiration 上传在最后一次写入之后的有效期，过期后会被清理，默认为 24 小时
+	Expiration time.Duration
+	// OnComplete 所有分片上传完成并且校验通过之后调用，可以把 upload.Path 移动到其他目录或者保存到 Storage 中。
+	// 返回 nil 时删除上传的状态和临时文件，返回错误时保留，客户端可以重试
+	OnComplete func(ctx *Context, upload *ChunkedUpload) error
+}
+
+// ChunkedUpload 一次分片上传的状态
+type ChunkedUpload struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Offset   int64  `json:"offset"`
+	// Checksum 整个文件的 sha256，十六进制，创建时提供则在完成时校验
+	Checksum  string    `json:"checksum,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Path 已经上传的数据所在的文件
+	Path string `json:"-"`
+}
+
+// ChunkedUploader 可以断点续传的分片上传，通过 routerGroup.ChunkedUpload 注册路由：
+//
+//	POST   {path}                 创建上传，body 为 {"filename": "a.zip", "size": 1024, "checksum": "<sha256 的十六进制，可选>"}
+//	PATCH  {path}/:id             上传分片，请求头 Upload-Offset 为分片的起始位置，必须等于已上传的字节数，
+//	                              可选的 Upload-Checksum 为分片的校验和，例如 "sha256 <base64>"，返回新的 Upload-Offset
+//	GET    {path}/:id             查询进度，网络中断之后从返回的 offset 继续上传
+//	POST   {path}/:id/complete    完成上传，校验整个文件并调用 OnComplete
+//	DELETE {path}/:id             取消上传
+type ChunkedUploader struct {
+	config      ChunkedUploadConfig
+	locks       [uploadLockStripes]sync.Mutex
+	lastCleanup time.Time
+	cleanupMu   sync.Mutex
+}
+
+// NewChunkedUploader 创建分片上传，Dir 不能为空
+func NewChunkedUploader(config ChunkedUploadConfig) (*ChunkedUploader, error) {
+	if config.Dir == "" {
+		return nil, errors.New("msgo: chunked upload dir is empty")
+	}
+	if config.Expiration <= 0 {
+		config.Expiration = 24 * time.Hour
+	}
+	if err := os.MkdirAll(config.Dir, 0750); err != nil {
+		return nil, err
+	}
+	return &ChunkedUploader{config: config}, nil
+}
+
+// ChunkedUpload 在 path 下注册分片上传的路由
+func (r *routerGroup) ChunkedUpload(path string, uploader *ChunkedUploader, middlewareFunc ...MiddlewareFunc) {
+	path = strings.TrimRight(path, "/")
+	r.HandleE(http.MethodPost, path, uploader.create, middlewareFunc...)
+	r.HandleE(http.MethodPatch, path+"/:id", uploader.patch, middlewareFunc...)
+	r.HandleE(http.MethodGet, path+"/:id", uploader.progress, middlewareFunc...)
+	r.HandleE(http.MethodPost, path+"/:id/complete", uploader.complete, middlewareFunc...)
+	r.HandleE(http.MethodDelete, path+"/:id", uploader.abort, middlewareFunc...)
+}
+
+type createChunkedUpload struct {
+	Filename string `json:"filename" validate:"required"`
+	Size     int64  `json:"size" validate:"gt=0"`
+	Checksum string `json:"checksum" validate:"omitempty,len=64,hexadecimal"`
+}
+
+func (u *ChunkedUploader) create(ctx *Context) error {
+	u.cleanupExpired()
+	var req createChunkedUpload
+	if err := ctx.BindJSON(&req); err != nil {
+		//BindJSON 已经写出了错误响应
+		return nil
+	}
+	if u.config.MaxSize > 0 && req.Size > u.config.MaxSize {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("upload size exceeds %d bytes", u.config.MaxSize))
+	}
+	id, err := newUploadID()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	upload := &ChunkedUpload{
+		ID:        id,
+		Filename:  SanitizeFilename(req.Filename),
+		Size:      req.Size,
+		Checksum:  strings.ToLower(req.Checksum),
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.config.Expiration),
+		Path:      u.dataPath(id),
+	}
+	f, err := os.OpenFile(upload.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	if err := u.save(upload); err != nil {
+		_ = os.Remove(upload.Path)
+		return err
+	}
+	ctx.W.Header().Set("Location", strings.TrimRight(ctx.R.URL.Path, "/")+"/"+id)
+	return u.respond(ctx, http.StatusCreated, upload)
+}
+
+// patch 分片写入失败或者校验和不一致时，文件会截断回写入之前的长度，客户端可以从原来的 offset 重试
+func (u *ChunkedUploader) patch(ctx *Context) error {
+	id := ctx.Param("id")
+	if !validUploadID(id) {
+		return errUploadNotFound()
+	}
+	unlock := u.lock(id)
+	defer unlock()
+	upload, err := u.load(id)
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.ParseInt(ctx.R.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Offset header")
+	}
+	if offset != upload.Offset {
+		ctx.W.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		return NewHTTPError(http.StatusConflict, fmt.Sprintf("offset mismatch, expected %d", upload.Offset))
+	}
+	var checker hash.Hash
+	var expected []byte
+	if header := ctx.R.Header.Get("Upload-Checksum"); header != "" {
+		if checker, expected, err = parseUploadChecksum(header); err != nil {
+			return NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	//分片不能超过剩余的字节数和分片大小的限制
+	limit := upload.Size - upload.Offset
+	if u.config.MaxChunkSize > 0 && u.config.MaxChunkSize < limit {
+		limit = u.config.MaxChunkSize
+	}
+	f, err := os.OpenFile(upload.Path, os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	var w io.Writer = f
+	if checker != nil {
+		w = io.MultiWriter(f, checker)
+	}
+	n, err := io.Copy(w, io.LimitReader(ctx.R.Body, limit+1))
+	rollback := func(he *HTTPError) error {
+		_ = f.Truncate(upload.Offset)
+		return he
+	}
+	if err != nil {
+		return rollback(NewHTTPError(http.StatusBadRequest, "read chunk failed").SetInternal(err))
+	}
+	if n > limit {
+		return rollback(NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("chunk exceeds %d bytes", limit)))
+	}
+	if checker != nil && subtle.ConstantTimeCompare(checker.Sum(nil), expected) != 1 {
+		return rollback(NewHTTPError(statusChecksumMismatch, "chunk checksum mismatch"))
+	}
+	if err := f.Sync(); err != nil {
+		return rollback(NewHTTPError(http.StatusInternalServerError).SetInternal(err))
+	}
+	upload.Offset += n
+	upload.ExpiresAt = time.Now().Add(u.config.Expiration)
+	if err := u.save(upload); err != nil {
+		return rollback(NewHTTPError(http.StatusInternalServerError).SetInternal(err))
+	}
+	return u.respond(ctx, http.StatusOK, upload)
+}
+
+func (u *ChunkedUploader) progress(ctx *Context) error {
+	id := ctx.Param("id")
+	if !validUploadID(id) {
+		return errUploadNotFound()
+	}
+	//load 会删除过期的上传，同样需要加锁
+	unlock := u.lock(id)
+	defer unlock()
+	upload, err := u.load(id)
+	if err != nil {
+		return err
+	}
+	return u.respond(ctx, http.StatusOK, upload)
+}
+
+func (u *ChunkedUploader) complete(ctx *Context) error {
+	id := ctx.Param("id")
+	if !validUploadID(id) {
+		return errUploadNotFound()
+	}
+	unlock := u.lock(id)
+	defer unlock()
+	upload, err := u.load(id)
+	if err != nil {
+		return err
+	}
+	if upload.Offset != upload.Size {
+		return NewHTTPError(http.StatusConflict, fmt.Sprintf("upload is incomplete, %d of %d bytes received", upload.Offset, upload.Size))
+	}
+	if upload.Checksum != "" {
+		sum, err := fileSHA256(upload.Path)
+		if err != nil {
+			return err
+		}
+		if sum != upload.Checksum {
+			return NewHTTPError(statusChecksumMismatch, "file checksum mismatch")
+		}
+	}
+	if u.config.OnComplete != nil {
+		if err := u.config.OnComplete(ctx, upload); err != nil {
+			return err
+		}
+	}
+	if err := u.remove(id); err != nil {
+		return err
+	}
+	if ctx.Written() {
+		return nil
+	}
+	return u.respond(ctx, http.StatusOK, upload)
+}
+
+func (u *ChunkedUploader) abort(ctx *Context) error {
+	id := ctx.Param("id")
+	if !validUploadID(id) {
+		return errUploadNotFound()
+	}
+	unlock := u.lock(id)
+	defer unlock()
+	if _, err := u.load(id); err != nil {
+		return err
+	}
+	if err := u.remove(id); err != nil {
+		return err
+	}
+	ctx.W.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (u *ChunkedUploader) respond(ctx *Context, status int, upload *ChunkedUpload) error {
+	ctx.W.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.W.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	return ctx.JSON(status, upload)
+}
+
+// Cleanup 删除所有已经过期的上传，以及超过 Expiration 仍然没有对应状态文件的数据文件和写入中断留下的临时文件，
+// 返回删除的上传和残留文件的数量
+func (u *ChunkedUploader) Cleanup() (int, error) {
+	entries, err := os.ReadDir(u.config.Dir)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, ".json"):
+			id := strings.TrimSuffix(name, ".json")
+			if !validUploadID(id) {
+				continue
+			}
+			unlock := u.lock(id)
+			upload, err := u.read(id)
+			//状态文件损坏时按照修改时间判断是否过期
+			expired := err == nil && time.Now().After(upload.ExpiresAt) ||
+				err != nil && !errors.Is(err, fs.ErrNotExist) && u.stale(entry)
+			if expired && u.remove(id) == nil {
+				count++
+			}
+			unlock()
+		case strings.HasSuffix(name, ".part"), strings.HasSuffix(name, ".json.tmp"):
+			id := strings.TrimSuffix(strings.TrimSuffix(name, ".part"), ".json.tmp")
+			if !validUploadID(id) || !u.stale(entry) {
+				continue
+			}
+			unlock := u.lock(id)
+			//.part 文件有对应的状态文件时由上面的分支处理
+			if _, err := os.Stat(u.statePath(id)); strings.HasSuffix(name, ".json.tmp") || errors.Is(err, fs.ErrNotExist) {
+				if err := os.Remove(filepath.Join(u.config.Dir, name)); err == nil {
+					count++
+				}
+			}
+			unlock()
+		}
+	}
+	return count, nil
+}
+
+// stale 文件在 Expiration 之内没有被修改过
+func (u *ChunkedUploader) stale(entry fs.DirEntry) bool {
+	info, err := entry.Info()
+	return err == nil && time.Since(info.ModTime()) > u.config.Expiration
+}
+
+// cleanupExpired 创建上传时顺便清理过期的上传，间隔不小于 Expiration 的十分之一
+func (u *ChunkedUploader) cleanupExpired() {
+	u.cleanupMu.Lock()
+	if time.Since(u.lastCleanup) < u.config.Expiration/10 {
+		u.cleanupMu.Unlock()
+		return
+	}
+	u.lastCleanup = time.Now()
+	u.cleanupMu.Unlock()
+	go func() {
+		_, _ = u.Cleanup()
+	}()
+}
+
+// lock 锁住 id 所在的分段，不同的上传可能共用同一个锁
+func (u *ChunkedUploader) lock(id string) func() {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	mu := &u.locks[h.Sum32()%uploadLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// load 读取上传的状态，不存在或者已经过期时返回 404
+func (u *ChunkedUploader) load(id string) (*ChunkedUpload, error) {
+	if !validUploadID(id) {
+		return nil, errUploadNotFound()
+	}
+	upload, err := u.read(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errUploadNotFound()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		_ = u.remove(id)
+		return nil, NewHTTPError(http.StatusNotFound, "upload expired")
+	}
+	return upload, nil
+}
+
+func (u *ChunkedUploader) read(id string) (*ChunkedUpload, error) {
+	data, err := os.ReadFile(u.statePath(id))
+	if err != nil {
+		return nil, err
+	}
+	upload := &ChunkedUpload{}
+	if err := json.Unmarshal(data, upload); err != nil {
+		return nil, err
+	}
+	upload.Path = u.dataPath(id)
+	return upload, nil
+}
+
+// save 先写临时文件再重命名，进程在写入过程中退出时不会留下损坏的状态
+func (u *ChunkedUploader) save(upload *ChunkedUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := u.statePath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.statePath(upload.ID))
+}
+
+func (u *ChunkedUploader) remove(id string) error {
+	if err := os.Remove(u.statePath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(u.dataPath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (u *ChunkedUploader) statePath(id string) string {
+	return filepath.Join(u.config.Dir, id+".json")
+}
+
+func (u *ChunkedUploader) dataPath(id string) string {
+	return filepath.Join(u.config.Dir, id+".part")
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validUploadID id 只能是 32 位的十六进制字符串，防止拼接路径时访问其他文件
+func validUploadID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func errUploadNotFound() *HTTPError {
+	return NewHTTPError(http.StatusNotFound, "upload not found")
+}
+
+// parseUploadChecksum 解析 tus 格式的校验和 "<算法> <base64>"，支持 sha1 和 sha256
+func parseUploadChecksum(header string) (hash.Hash, []byte, error) {
+	algorithm, value, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, nil, errors.New("invalid Upload-Checksum header")
+	}
+	expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, nil, errors.New("invalid Upload-Checksum header")
+	}
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		return sha1.New(), expected, nil
+	case "sha256":
+		return sha256.New(), expected, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported checksum algorithm %s", algorithm)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,233 @@
+package msgo
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type chunkedUploadTest struct {
+	t        *testing.T
+	engine   *Engine
+	uploader *ChunkedUploader
+	dir      string
+}
+
+func newChunkedUploadTest(t *testing.T, config ChunkedUploadConfig) *chunkedUploadTest {
+	t.Helper()
+	config.Dir = t.TempDir()
+	uploader, err := NewChunkedUploader(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine()
+	engine.Group("api").ChunkedUpload("/uploads", uploader)
+	return &chunkedUploadTest{t: t, engine: engine, uploader: uploader, dir: config.Dir}
+}
+
+func (c *chunkedUploadTest) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	r := httptest.NewRequest(method, "/api/uploads"+path, strings.NewReader(body))
+	if method == http.MethodPost && body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	c.engine.ServeHTTP(w, r)
+	return w
+}
+
+// create 创建上传并返回 id
+func (c *chunkedUploadTest) create(data string, withChecksum bool) string {
+	c.t.Helper()
+	body := map[string]any{"filename": "../a.txt", "size": len(data)}
+	if withChecksum {
+		sum := sha256.Sum256([]byte(data))
+		body["checksum"] = hex.EncodeToString(sum[:])
+	}
+	b, _ := json.Marshal(body)
+	w := c.do(http.MethodPost, "", string(b))
+	if w.Code != http.StatusCreated {
+		c.t.Fatalf("create: status %d %s", w.Code, w.Body.String())
+	}
+	var upload ChunkedUpload
+	if err := json.Unmarshal(w.Body.Bytes(), &upload); err != nil {
+		c.t.Fatal(err)
+	}
+	if upload.Filename != "a.txt" {
+		c.t.Fatalf("filename = %q, want sanitized a.txt", upload.Filename)
+	}
+	if w.Header().Get("Location") != "/api/uploads/"+upload.ID {
+		c.t.Fatalf("Location = %q", w.Header().Get("Location"))
+	}
+	return upload.ID
+}
+
+func (c *chunkedUploadTest) expect(w *httptest.ResponseRecorder, status int, offset string) {
+	c.t.Helper()
+	if w.Code != status {
+		c.t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if offset != "" && w.Header().Get("Upload-Offset") != offset {
+		c.t.Fatalf("Upload-Offset = %q, want %s", w.Header().Get("Upload-Offset"), offset)
+	}
+}
+
+func (c *chunkedUploadTest) partSize(id string) int64 {
+	c.t.Helper()
+	info, err := os.Stat(filepath.Join(c.dir, id+".part"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func chunkChecksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestChunkedUpload(t *testing.T) {
+	var completed []byte
+	c := newChunkedUploadTest(t, ChunkedUploadConfig{
+		MaxChunkSize: 4,
+		OnComplete: func(ctx *Context, upload *ChunkedUpload) error {
+			var err error
+			completed, err = os.ReadFile(upload.Path)
+			return err
+		},
+	})
+	id := c.create("hello world", true)
+	path := "/" + id
+
+	c.expect(c.do(http.MethodPatch, path, "hell", "Upload-Offset", "0"), http.StatusOK, "4")
+	//重复发送同一个分片时 offset 不一致，返回当前的 offset
+	c.expect(c.do(http.MethodPatch, path, "hell", "Upload-Offset", "0"), http.StatusConflict, "4")
+	c.expect(c.do(http.MethodPatch, path, "o wo", "Upload-Offset", "x"), http.StatusBadRequest, "")
+
+	//校验和不一致时回滚
+	c.expect(c.do(http.MethodPatch, path, "o wo", "Upload-Offset", "4", "Upload-Checksum", chunkChecksum("xxxx")), statusChecksumMismatch, "")
+	if size := c.partSize(id); size != 4 {
+		t.Fatalf("part size after checksum mismatch = %d, want 4", size)
+	}
+	c.expect(c.do(http.MethodPatch, path, "o wo", "Upload-Offset", "4", "Upload-Checksum", "md5 AAAA"), http.StatusBadRequest, "")
+	c.expect(c.do(http.MethodPatch, path, "o wo", "Upload-Offset", "4", "Upload-Checksum", chunkChecksum("o wo")), http.StatusOK, "8")
+
+	//超过 MaxChunkSize 和剩余字节数时回滚
+	c.expect(c.do(http.MethodPatch, path, "rld!!", "Upload-Offset", "8"), http.StatusRequestEntityTooLarge, "")
+	if size := c.partSize(id); size != 8 {
+		t.Fatalf("part size after oversized chunk = %d, want 8", size)
+	}
+	c.expect(c.do(http.MethodPost, path+"/complete", ""), http.StatusConflict, "")
+	c.expect(c.do(http.MethodPatch, path, "rld", "Upload-Offset", "8"), http.StatusOK, "11")
+	c.expect(c.do(http.MethodGet, path, ""), http.StatusOK, "11")
+
+	c.expect(c.do(http.MethodPost, path+"/complete", ""), http.StatusOK, "11")
+	if string(completed) != "hello world" {
+		t.Fatalf("OnComplete got %q", completed)
+	}
+	c.expect(c.do(http.MethodGet, path, ""), http.StatusNotFound, "")
+	if entries, _ := os.ReadDir(c.dir); len(entries) != 0 {
+		t.Fatalf("%d files left after complete", len(entries))
+	}
+}
+
+func TestChunkedUploadFileChecksumMismatch(t *testing.T) {
+	c := newChunkedUploadTest(t, ChunkedUploadConfig{})
+	id := c.create("hello", true)
+	c.expect(c.do(http.MethodPatch, "/"+id, "HELLO", "Upload-Offset", "0"), http.StatusOK, "5")
+	c.expect(c.do(http.MethodPost, "/"+id+"/complete", ""), statusChecksumMismatch, "")
+	//校验失败时保留上传，客户端可以取消
+	c.expect(c.do(http.MethodDelete, "/"+id, ""), http.StatusNoContent, "")
+	c.expect(c.do(http.MethodGet, "/"+id, ""), http.StatusNotFound, "")
+}
+
+func TestChunkedUploadCreateValidation(t *testing.T) {
+	c := newChunkedUploadTest(t, ChunkedUploadConfig{MaxSize: 10})
+	c.expect(c.do(http.MethodPost, "", `{"size":0}`), http.StatusBadRequest, "")
+	c.expect(c.do(http.MethodPost, "", `{"filename":"a","size":5,"checksum":"zz"}`), http.StatusBadRequest, "")
+	c.expect(c.do(http.MethodPost, "", `{"filename":"a","size":11}`), http.StatusRequestEntityTooLarge, "")
+}
+
+func TestChunkedUploadInvalidID(t *testing.T) {
+	c := newChunkedUploadTest(t, ChunkedUploadConfig{})
+	for _, id := range []string{"abc", strings.Repeat("z", 32), strings.Repeat("0", 32)} {
+		c.expect(c.do(http.MethodPatch, "/"+id, "x", "Upload-Offset", "0"), http.StatusNotFound, "")
+		c.expect(c.do(http.MethodGet, "/"+id, ""), http.StatusNotFound, "")
+		c.expect(c.do(http.MethodDelete, "/"+id, ""), http.StatusNotFound, "")
+		c.expect(c.do(http.MethodPost, "/"+id+"/complete", ""), http.StatusNotFound, "")
+	}
+}
+
+func TestChunkedUploadExpiration(t *testing.T) {
+	c := newChunkedUploadTest(t, ChunkedUploadConfig{Expiration: 20 * time.Millisecond})
+	id := c.create("hello", false)
+	time.Sleep(30 * time.Millisecond)
+	c.expect(c.do(http.MethodPatch, "/"+id, "hello", "Upload-Offset", "0"), http.StatusNotFound, "")
+	if _, err := os.Stat(filepath.Join(c.dir, id+".part")); !os.IsNotExist(err) {
+		t.Fatalf("expired part file not removed: %v", err)
+	}
+}
+
+func TestChunkedUploadCleanup(t *testing.T) {
+	c := newChunkedUploadTest(t, ChunkedUploadConfig{Expiration: time.Hour})
+	active := c.create("hello", false)
+	expired := c.create("hello", false)
+
+	//修改状态文件中的过期时间
+	state := filepath.Join(c.dir, expired+".json")
+	data, _ := os.ReadFile(state)
+	var upload ChunkedUpload
+	_ = json.Unmarshal(data, &upload)
+	upload.ExpiresAt = time.Now().Add(-time.Minute)
+	data, _ = json.Marshal(upload)
+	if err := os.WriteFile(state, data, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	//残留的文件：没有状态文件的 .part、写入中断的 .json.tmp，以及还在有效期内的 .part
+	old := time.Now().Add(-2 * time.Hour)
+	orphan := strings.Repeat("a", 32)
+	recent := strings.Repeat("b", 32)
+	for _, name := range []string{orphan + ".part", orphan + ".json.tmp", recent + ".part", "other.txt"} {
+		path := filepath.Join(c.dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(name, recent) {
+			_ = os.Chtimes(path, old, old)
+		}
+	}
+
+	count, err := c.uploader.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("Cleanup removed %d, want 3", count)
+	}
+	var names []string
+	entries, _ := os.ReadDir(c.dir)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{active + ".json", active + ".part", recent + ".part", "other.txt"}
+	for _, name := range want {
+		if !containsString(names, name) {
+			t.Fatalf("%s removed, left %v", name, names)
+		}
+	}
+	if len(names) != len(want) {
+		t.Fatalf("left %v, want %v", names, want)
+	}
+}
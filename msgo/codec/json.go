@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONCodec JSON 编解码器，render 和 binding 都通过它处理 JSON，
+// 可以替换成 json-iterator、sonic 等更快的实现
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	MarshalIndent(v any, prefix, indent string) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+type JSONEncoder interface {
+	SetEscapeHTML(on bool)
+	SetIndent(prefix, indent string)
+	Encode(v any) error
+}
+
+type JSONDecoder interface {
+	UseNumber()
+	DisallowUnknownFields()
+	Decode(v any) error
+}
+
+// JSON 当前使用的 JSON 编解码器，默认为标准库 encoding/json
+var JSON JSONCodec = stdJSON{}
+
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSON) MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+func (stdJSON) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (stdJSON) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (stdJSON) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
@@ -0,0 +1,206 @@
+package msgo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ComputeETag 根据内容计算 ETag，weak 为 true 时返回 W/"..." 形式的弱 ETag
+func ComputeETag(data []byte, weak bool) string {
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// SetETag 设置响应的 ETag，etag 没有引号时自动加上，weak 为 true 时设置为弱 ETag
+func (c *Context) SetETag(etag string, weak bool) {
+	etag = strings.TrimPrefix(etag, "W/")
+	if !strings.HasPrefix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	if weak {
+		etag = "W/" + etag
+	}
+	c.W.Header().Set("ETag", etag)
+}
+
+// ServeContent 输出 content，支持 Range（单个和多个范围）、If-Match、If-None-Match、
+// If-Modified-Since、If-Unmodified-Since 和 If-Range，具体的处理由 http.ServeContent 完成。
+// 响应头中已经设置的 ETag 会参与条件判断，modTime 为零值时不设置 Last-Modified；
+// 没有设置 Content-Type 时根据 name 的扩展名或者内容推断。
+// Data、DataFromReader（reader 可以 Seek 时）、ServeData、FileFromFS 和 FileAttachment 都通过它输出
+func (c *Context) ServeContent(name string, modTime time.Time, content io.ReadSeeker) {
+	http.ServeContent(c.W, c.R, name, modTime, content)
+}
+
+// rangeable 只有 GET、HEAD 请求的 200 响应才处理 Range 和条件请求，其他状态码原样输出
+func (c *Context) rangeable(status int) bool {
+	return status == http.StatusOK && (c.R.Method == http.MethodGet || c.R.Method == http.MethodHead)
+}
+
+// ServeData 和 ServeContent 相同，没有设置 ETag 时根据 data 计算强 ETag
+func (c *Context) ServeData(name string, modTime time.Time, data []byte) {
+	if c.W.Header().Get("ETag") == "" {
+		c.W.Header().Set("ETag", ComputeETag(data, false))
+	}
+	c.ServeContent(name, modTime, bytes.NewReader(data))
+}
+
+// NotModified 设置 ETag 和 Last-Modified，请求的 If-None-Match 或者 If-Modified-Since 表明
+// 客户端的缓存仍然有效时写出 304 并返回 true，调用方不需要再生成响应。
+// 用于能够以较低的代价得到版本号的动态响应，例如：
+//
+//	if ctx.NotModified(`"`+strconv.Itoa(article.Version)+`"`, article.UpdatedAt) {
+//		return nil
+//	}
+//	return ctx.JSON(http.StatusOK, article)
+func (c *Context) NotModified(etag string, modTime time.Time) bool {
+	header := c.W.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !isZeroTime(modTime) {
+		header.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if c.R.Method != http.MethodGet && c.R.Method != http.MethodHead {
+		return false
+	}
+	if !checkNotModified(c.R, etag, modTime) {
+		return false
+	}
+	writeNotModified(c.W)
+	return true
+}
+
+// ETag 为 GET 和 HEAD 请求的 200 响应计算 ETag，请求的 If-None-Match 匹配时返回 304。
+// 需要缓存整个响应之后才能计算，适用于 JSON 等较小的动态响应；
+// 处理函数已经设置了 ETag，或者调用了 Flush、Hijack 时不处理
+func ETag(weak bool) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if ctx.R.Method != http.MethodGet && ctx.R.Method != http.MethodHead {
+				next(ctx)
+				return
+			}
+			w := &etagWriter{ResponseWriter: ctx.W}
+			ctx.W = w
+			defer func() {
+				ctx.W = w.ResponseWriter
+			}()
+			next(ctx)
+			if w.passthrough {
+				return
+			}
+			header := w.Header()
+			if ctx.writermem.Status() == http.StatusOK && header.Get("ETag") == "" {
+				etag := ComputeETag(w.body.Bytes(), weak)
+				header.Set("ETag", etag)
+				if checkNotModified(ctx.R, etag, time.Time{}) {
+					writeNotModified(w.ResponseWriter)
+					return
+				}
+			}
+			_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		}
+	}
+}
+
+// etagWriter 缓存 body，Flush 或者 Hijack 之后直接写出
+type etagWriter struct {
+	http.ResponseWriter
+	body        bytes.Buffer
+	passthrough bool
+	wrote       bool
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	w.wrote = true
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+// Written body 写入缓存之后也认为已经写出，错误处理不能再追加响应
+func (w *etagWriter) Written() bool {
+	return w.wrote || responseWritten(w.ResponseWriter)
+}
+
+func (w *etagWriter) Flush() {
+	w.flushBody()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+func (w *etagWriter) flushBody() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+}
+
+// checkNotModified 有 If-None-Match 时只比较 ETag（弱比较），否则比较 If-Modified-Since
+func checkNotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagWeakMatch(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || isZeroTime(modTime) {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	//HTTP 时间只精确到秒
+	return !modTime.Truncate(time.Second).After(t)
+}
+
+// etagWeakMatch 判断 If-None-Match 中是否有和 etag 弱匹配的值，忽略 W/ 前缀
+func etagWeakMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified 304 响应不能带 body，和 body 相关的响应头也要去掉
+func writeNotModified(w http.ResponseWriter) {
+	header := w.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
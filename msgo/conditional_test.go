@@ -0,0 +1,212 @@
+package msgo
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var conditionalModTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newConditionalEngine() *Engine {
+	engine := NewEngine()
+	g := engine.Group("t")
+	g.Get("/serve", func(ctx *Context) {
+		ctx.ServeData("a.txt", conditionalModTime, []byte("0123456789"))
+	})
+	g.Get("/data", func(ctx *Context) {
+		_ = ctx.Data(http.StatusOK, "application/octet-stream", []byte("0123456789"))
+	})
+	g.Get("/data-etag", func(ctx *Context) {
+		ctx.SetETag("v1", false)
+		_ = ctx.Data(http.StatusOK, "application/octet-stream", []byte("0123456789"))
+	})
+	g.Get("/data-created", func(ctx *Context) {
+		_ = ctx.Data(http.StatusCreated, "text/plain", []byte("0123456789"))
+	})
+	g.Get("/reader", func(ctx *Context) {
+		_ = ctx.DataFromReader(http.StatusOK, -1, "text/plain", strings.NewReader("0123456789"), map[string]string{"X-A": "1"})
+	})
+	g.Get("/stream", func(ctx *Context) {
+		_ = ctx.DataFromReader(http.StatusOK, 10, "text/plain", io.LimitReader(strings.NewReader("0123456789"), 10), nil)
+	})
+	g.Get("/version", func(ctx *Context) {
+		if ctx.NotModified(`"v1"`, conditionalModTime) {
+			return
+		}
+		_ = ctx.String(http.StatusOK, "fresh")
+	})
+	g.Get("/json", func(ctx *Context) {
+		_ = ctx.JSON(http.StatusOK, map[string]int{"a": 1})
+	}, ETag(true))
+	g.HandleE(http.MethodGet, "/json-error", func(ctx *Context) error {
+		_ = ctx.JSON(http.StatusOK, map[string]int{"a": 1})
+		return errors.New("failed after writing")
+	}, ETag(false))
+	g.Get("/json-etag", func(ctx *Context) {
+		ctx.SetETag("custom", false)
+		_ = ctx.JSON(http.StatusOK, map[string]int{"a": 1})
+	}, ETag(false))
+	g.Get("/json-missing", func(ctx *Context) {
+		_ = ctx.JSON(http.StatusNotFound, map[string]int{"a": 1})
+	}, ETag(false))
+	return engine
+}
+
+func conditionalGet(engine *Engine, target string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func expectResponse(t *testing.T, w *httptest.ResponseRecorder, code int, body string) {
+	t.Helper()
+	if w.Code != code || w.Body.String() != body {
+		t.Fatalf("got %d %q, want %d %q", w.Code, w.Body.String(), code, body)
+	}
+}
+
+func TestServeDataRange(t *testing.T) {
+	engine := newConditionalEngine()
+	w := conditionalGet(engine, "/t/serve")
+	expectResponse(t, w, http.StatusOK, "0123456789")
+	if w.Header().Get("Accept-Ranges") != "bytes" || w.Header().Get("ETag") == "" {
+		t.Fatalf("headers = %v", w.Header())
+	}
+
+	w = conditionalGet(engine, "/t/serve", "Range", "bytes=2-4")
+	expectResponse(t, w, http.StatusPartialContent, "234")
+	if w.Header().Get("Content-Range") != "bytes 2-4/10" {
+		t.Fatalf("Content-Range = %q", w.Header().Get("Content-Range"))
+	}
+	expectResponse(t, conditionalGet(engine, "/t/serve", "Range", "bytes=-3"), http.StatusPartialContent, "789")
+	if w := conditionalGet(engine, "/t/serve", "Range", "bytes=20-30"); w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("unsatisfiable range: %d", w.Code)
+	}
+
+	w = conditionalGet(engine, "/t/serve", "Range", "bytes=0-1,5-6")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("multiple ranges: %d", w.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+" "+string(data))
+	}
+	if strings.Join(parts, ",") != "bytes 0-1/10 01,bytes 5-6/10 56" {
+		t.Fatalf("parts = %v", parts)
+	}
+}
+
+func TestServeDataConditional(t *testing.T) {
+	engine := newConditionalEngine()
+	etag := conditionalGet(engine, "/t/serve").Header().Get("ETag")
+
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-None-Match", etag), http.StatusNotModified, "")
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-None-Match", `"other", `+etag), http.StatusNotModified, "")
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-None-Match", `"other"`), http.StatusOK, "0123456789")
+
+	ims := conditionalModTime.Format(http.TimeFormat)
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-Modified-Since", ims), http.StatusNotModified, "")
+	before := conditionalModTime.Add(-time.Hour).Format(http.TimeFormat)
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-Modified-Since", before), http.StatusOK, "0123456789")
+	//If-None-Match 优先于 If-Modified-Since
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-None-Match", `"other"`, "If-Modified-Since", ims), http.StatusOK, "0123456789")
+
+	//If-Range 不匹配时返回完整内容
+	expectResponse(t, conditionalGet(engine, "/t/serve", "Range", "bytes=2-4", "If-Range", etag), http.StatusPartialContent, "234")
+	expectResponse(t, conditionalGet(engine, "/t/serve", "Range", "bytes=2-4", "If-Range", `"other"`), http.StatusOK, "0123456789")
+	expectResponse(t, conditionalGet(engine, "/t/serve", "If-Match", `"other"`), http.StatusPreconditionFailed, "")
+}
+
+func TestDataRange(t *testing.T) {
+	engine := newConditionalEngine()
+	expectResponse(t, conditionalGet(engine, "/t/data", "Range", "bytes=2-4"), http.StatusPartialContent, "234")
+	expectResponse(t, conditionalGet(engine, "/t/data-etag", "If-None-Match", `"v1"`), http.StatusNotModified, "")
+	//非 200 的响应原样输出
+	expectResponse(t, conditionalGet(engine, "/t/data-created", "Range", "bytes=2-4"), http.StatusCreated, "0123456789")
+
+	w := conditionalGet(engine, "/t/reader", "Range", "bytes=2-4")
+	expectResponse(t, w, http.StatusPartialContent, "234")
+	if w.Header().Get("X-A") != "1" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("headers = %v", w.Header())
+	}
+	//不能 Seek 的 reader 不支持 Range
+	expectResponse(t, conditionalGet(engine, "/t/stream", "Range", "bytes=2-4"), http.StatusOK, "0123456789")
+}
+
+func TestNotModified(t *testing.T) {
+	engine := newConditionalEngine()
+	w := conditionalGet(engine, "/t/version")
+	expectResponse(t, w, http.StatusOK, "fresh")
+	if w.Header().Get("ETag") != `"v1"` || w.Header().Get("Last-Modified") != conditionalModTime.Format(http.TimeFormat) {
+		t.Fatalf("headers = %v", w.Header())
+	}
+	expectResponse(t, conditionalGet(engine, "/t/version", "If-None-Match", `W/"v1"`), http.StatusNotModified, "")
+	expectResponse(t, conditionalGet(engine, "/t/version", "If-None-Match", "*"), http.StatusNotModified, "")
+	expectResponse(t, conditionalGet(engine, "/t/version", "If-Modified-Since", conditionalModTime.Format(http.TimeFormat)), http.StatusNotModified, "")
+	expectResponse(t, conditionalGet(engine, "/t/version", "If-Modified-Since", "invalid"), http.StatusOK, "fresh")
+}
+
+func TestETagMiddleware(t *testing.T) {
+	engine := newConditionalEngine()
+	w := conditionalGet(engine, "/t/json")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("got %d, ETag %q", w.Code, etag)
+	}
+	w = conditionalGet(engine, "/t/json", "If-None-Match", etag)
+	expectResponse(t, w, http.StatusNotModified, "")
+	if w.Header().Get("Content-Type") != "" || w.Header().Get("ETag") != etag {
+		t.Fatalf("304 headers = %v", w.Header())
+	}
+
+	//处理函数写出响应之后返回的错误不能追加到缓存的 body 中
+	w = conditionalGet(engine, "/t/json-error")
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), "{") != 1 {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+
+	if w := conditionalGet(engine, "/t/json-etag", "If-None-Match", `"custom"`); w.Header().Get("ETag") != `"custom"` || w.Code != http.StatusOK {
+		t.Fatalf("handler ETag should be kept: %d %v", w.Code, w.Header())
+	}
+	if w := conditionalGet(engine, "/t/json-missing"); w.Header().Get("ETag") != "" || w.Code != http.StatusNotFound {
+		t.Fatalf("non-200 response: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestETagMiddlewareWritten(t *testing.T) {
+	engine := NewEngine()
+	var before, after bool
+	engine.Group("t").Get("/json", func(ctx *Context) {
+		before = ctx.Written()
+		_ = ctx.JSON(http.StatusOK, 1)
+		after = ctx.Written()
+	}, ETag(false))
+	conditionalGet(engine, "/t/json")
+	if before || !after {
+		t.Fatalf("Written before = %v, after = %v", before, after)
+	}
+}
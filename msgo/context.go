@@ -1,9 +1,11 @@
 package msgo
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/mis403/msgo/binding"
+	"github.com/mis403/msgo/codec"
 	"github.com/mis403/msgo/render"
 	"html/template"
 	"io"
@@ -11,9 +13,9 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
 	"reflect"
 	"strings"
+	"time"
 )
 
 const defaultMaxMemory = 32 << 20
@@ -22,12 +24,98 @@ type Context struct {
 	W                     http.ResponseWriter
 	R                     *http.Request
 	engine                *Engine
+	writermem             responseWriter
 	queryCache            url.Values
 	formCache             url.Values
+	params                map[string]string
+	requestBody           io.ReadCloser
+	bodyBytes             []byte
+	templateData          map[string]any
 	DisallowUnknownFields bool
 	IsValidate            bool
 }
 
+// 重置从对象池中取出的 Context
+func (c *Context) reset() {
+	c.queryCache = nil
+	c.formCache = nil
+	c.params = nil
+	c.requestBody = c.R.Body
+	c.bodyBytes = nil
+	c.templateData = nil
+	c.DisallowUnknownFields = false
+	c.IsValidate = false
+}
+
+// setBodyLimit 限制请求 body 的大小，size 小于等于 0 时不限制
+func (c *Context) setBodyLimit(size int64) {
+	if c.requestBody == nil || c.bodyBytes != nil {
+		return
+	}
+	if size <= 0 {
+		c.R.Body = c.requestBody
+		return
+	}
+	c.R.Body = http.MaxBytesReader(c.writermem.ResponseWriter, c.requestBody, size)
+}
+
+// BodyLimit 路由级别的请求 body 大小限制，覆盖路由组和 Engine 的设置，小于 0 表示不限制
+func BodyLimit(size int64) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.setBodyLimit(size)
+			next(ctx)
+		}
+	}
+}
+
+// GetRawData 读取请求 body 并缓存，之后 c.R.Body 可以再次读取
+func (c *Context) GetRawData() ([]byte, error) {
+	if c.bodyBytes == nil {
+		if c.R.Body == nil {
+			return nil, errors.New("invalid request")
+		}
+		body, err := io.ReadAll(c.R.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.bodyBytes = body
+	}
+	c.R.Body = io.NopCloser(bytes.NewReader(c.bodyBytes))
+	return c.bodyBytes, nil
+}
+
+// Written 判断响应是否已经写出，c.W 被中间件包装时以包装之后的 ResponseWriter 为准，
+// 例如 ETag 中间件缓存了 body 时也认为已经写出
+func (c *Context) Written() bool {
+	if w, ok := c.W.(interface{ Written() bool }); ok {
+		return w.Written()
+	}
+	return c.writermem.Written()
+}
+
+// HandleError 将错误交给 Engine.ErrorHandler 处理
+func (c *Context) HandleError(err error) {
+	if err == nil {
+		return
+	}
+	handler := c.engine.ErrorHandler
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(c, err)
+}
+
+// Param 获取路径参数，/get/:id 中的 id
+func (c *Context) Param(key string) string {
+	value, _ := c.GetParam(key)
+	return value
+}
+func (c *Context) GetParam(key string) (string, bool) {
+	value, ok := c.params[key]
+	return value, ok
+}
+
 func (c *Context) QueryMap(key string) (dict map[string]string) {
 	dict, _ = c.GetQueryMap(key)
 	return
@@ -99,34 +187,6 @@ func (c *Context) initPostFormCache() {
 	}
 }
 
-// 解析文件
-func (c *Context) FormFile(name string) *multipart.FileHeader {
-	file, header, err := c.R.FormFile(name)
-	if err != nil {
-		log.Println(err)
-	}
-	defer file.Close()
-	return header
-}
-
-// 下载功能提取
-func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
-	src, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, src)
-	return err
-}
-
 // 下载
 func (c *Context) MultipartForm() (*multipart.Form, error) {
 	err := c.R.ParseMultipartForm(defaultMaxMemory)
@@ -166,7 +226,7 @@ func (c *Context) HTMLTemplateGlob(name string, data any, pattern string) error
 	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	// 创建一个新的模板，并将其赋值给变量 t。模板的名字由传入的 name 参数指定。
-	t := template.New(name)
+	t := template.New(name).Funcs(c.engine.templateFuncs())
 
 	// 解析指定模式（pattern 参数）的所有文件，并将解析的模板存储在 t 中。
 	// 解析的模板可以用来执行后续的渲染操作。同时，它还返回一个 error 类型的值 err，表示解析过程中是否出现了错误。
@@ -184,48 +244,183 @@ func (c *Context) HTMLTemplateGlob(name string, data any, pattern string) error
 	return err
 }
 
+// SetTemplateData 设置当前请求的模板数据，例如在中间件中设置当前用户、CSRF token、语言等，
+// Template 渲染时会合并到 data 中
+func (c *Context) SetTemplateData(key string, value any) {
+	if c.templateData == nil {
+		c.templateData = make(map[string]any)
+	}
+	c.templateData[key] = value
+}
+
+// TemplateData 返回当前请求设置的模板数据
+func (c *Context) TemplateData() map[string]any {
+	return c.templateData
+}
+
+// mergeTemplateData 合并请求的模板数据，同名的 key 以 data 为准。data 为 nil 或者 key 为字符串的 map
+// （包括 map[string]string、gin.H 这样的具名类型）时可以合并；设置了请求的模板数据而 data 是结构体等
+// 其他类型时返回错误，避免这些数据被静默丢弃，这时需要改用 map 或者自行把需要的值放到结构体中
+func (c *Context) mergeTemplateData(data any) (any, error) {
+	if len(c.templateData) == 0 {
+		return data, nil
+	}
+	merged := make(map[string]any, len(c.templateData))
+	for k, v := range c.templateData {
+		merged[k] = v
+	}
+	if data == nil {
+		return merged, nil
+	}
+	if values, ok := data.(map[string]any); ok {
+		for k, v := range values {
+			merged[k] = v
+		}
+		return merged, nil
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("msgo: template data of type %T cannot be merged with SetTemplateData values, use a map with string keys", data)
+	}
+	iter := v.MapRange()
+	for iter.Next() {
+		merged[iter.Key().String()] = iter.Value().Interface()
+	}
+	return merged, nil
+}
+
+// Template 渲染已加载的模板，使用布局时 name 为页面相对模板根目录的路径，例如 admin/index.html。
+// 设置了 SetTemplateData 时 data 必须为 nil 或者 key 为字符串的 map，两者合并之后传给模板
 func (c *Context) Template(name string, data any) error {
-	return c.Render(http.StatusOK, &render.HTML{
-		Data:       data,
-		Name:       name,
-		Template:   c.engine.HTMLRender.Template,
-		IsTemplate: true,
-	})
+	if c.engine.HTMLRender == nil {
+		return errors.New("msgo: no template loaded")
+	}
+	merged, err := c.mergeTemplateData(data)
+	if err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, c.engine.HTMLRender.Instance(name, merged))
 }
 func (c *Context) JSON(status int, data any) error {
-	// 设置响应头中的 Content-Type 字段为 text/html; charset=utf-8，表示响应的内容类型为 HTML，并且使用 UTF-8 字符编码。
-	c.W.Header().Set("Content-Type", "text/json; charset=utf-8")
-	c.W.WriteHeader(status)
-
 	return c.Render(status, &render.JSON{Data: data})
 }
+
+// IndentedJSON 格式化输出 JSON，比较耗费资源，建议只在开发环境使用
+func (c *Context) IndentedJSON(status int, data any) error {
+	return c.Render(status, &render.IndentedJSON{Data: data})
+}
+
+// SecureJSON 数组类型的 JSON 会加上 Engine 设置的前缀，防止 JSON 劫持
+func (c *Context) SecureJSON(status int, data any) error {
+	return c.Render(status, &render.SecureJSON{Prefix: c.engine.secureJSONPrefix, Data: data})
+}
+
+// JSONP 从 query 参数 callback 中取出回调函数名，没有 callback 时输出普通的 JSON，
+// callback 不是合法的标识符时返回 400
+func (c *Context) JSONP(status int, data any) error {
+	callback := c.DefaultQuery("callback", "")
+	if callback == "" {
+		return c.JSON(status, data)
+	}
+	if !render.ValidJSONPCallback(callback) {
+		return NewHTTPError(http.StatusBadRequest, "invalid callback").SetInternal(render.ErrInvalidCallback)
+	}
+	return c.Render(status, &render.JsonpJSON{Callback: callback, Data: data})
+}
+
+// AsciiJSON 非 ASCII 字符会转义为 \uXXXX
+func (c *Context) AsciiJSON(status int, data any) error {
+	return c.Render(status, &render.AsciiJSON{Data: data})
+}
+
+// PureJSON 不转义 HTML 字符，<b> 不会被转换为 \u003cb\u003e
+func (c *Context) PureJSON(status int, data any) error {
+	return c.Render(status, &render.PureJSON{Data: data})
+}
 func (c *Context) XML(status int, data any) error {
 
 	return c.Render(status, &render.XML{
 		Data: data,
 	})
 }
-func (c *Context) FileAttachment(filepath, filename string) {
+func (c *Context) YAML(status int, data any) error {
+	return c.Render(status, &render.YAML{
+		Data: data,
+	})
+}
+func (c *Context) TOML(status int, data any) error {
+	return c.Render(status, &render.TOML{
+		Data: data,
+	})
+}
+func (c *Context) MsgPack(status int, data any) error {
+	return c.Render(status, &render.MsgPack{
+		Data: data,
+	})
+}
 
-	//判断文件格式
-	if isASCII(filename) {
-		c.W.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
-	} else {
-		c.W.Header().Set("Content-Disposition", `attachment; filename*=UTF-8''`+url.QueryEscape(filename))
-	}
-	http.ServeFile(c.W, c.R, filepath)
+// ProtoBuf data 需要实现 proto.Message
+func (c *Context) ProtoBuf(status int, data any) error {
+	return c.Render(status, &render.ProtoBuf{
+		Data: data,
+	})
 }
 
-// 从文件系统下载  filepath是相对于文件系统路径
-func (c *Context) FileFromFS(filepath string, fs http.FileSystem) {
-	defer func(old string) {
-		fmt.Println(old) //old 为url
-		c.R.URL.Path = old
-	}(c.R.URL.Path)
+// Data 输出任意字节数据。GET、HEAD 请求的 200 响应通过 ServeContent 输出，支持 Range，
+// 调用之前设置了 ETag 响应头时同时支持 If-None-Match 等条件请求
+func (c *Context) Data(status int, contentType string, data []byte) error {
+	if c.rangeable(status) {
+		c.W.Header().Set("Content-Type", contentType)
+		c.ServeContent("", time.Time{}, bytes.NewReader(data))
+		return nil
+	}
+	return c.Render(status, &render.Data{
+		ContentType: contentType,
+		Data:        data,
+	})
+}
 
-	c.R.URL.Path = filepath
+// DataFromReader 从 reader 中读取数据输出，contentLength 小于 0 时不设置 Content-Length，extraHeaders 为额外的响应头。
+// reader 实现了 io.ReadSeeker 时和 Data 一样支持 Range 和条件请求，长度通过 Seek 得到，忽略 contentLength；
+// 其他的 reader 只能顺序读取，不支持 Range
+func (c *Context) DataFromReader(status int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) error {
+	if seeker, ok := reader.(io.ReadSeeker); ok && c.rangeable(status) {
+		header := c.W.Header()
+		for k, v := range extraHeaders {
+			if header.Get(k) == "" {
+				header.Set(k, v)
+			}
+		}
+		header.Set("Content-Type", contentType)
+		c.ServeContent("", time.Time{}, seeker)
+		return nil
+	}
+	return c.Render(status, &render.Reader{
+		ContentType:   contentType,
+		ContentLength: contentLength,
+		Reader:        reader,
+		Headers:       extraHeaders,
+	})
+}
 
-	http.FileServer(fs).ServeHTTP(c.W, c.R)
+// Stream 流式输出，每次调用 step 之后刷新缓冲区，step 返回 false 时结束。
+// 客户端断开连接时返回 true
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	clientGone := c.R.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return true
+		default:
+			keepOpen := step(c.W)
+			if flusher, ok := c.W.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			if !keepOpen {
+				return false
+			}
+		}
+	}
 }
 
 // 重定向
@@ -245,130 +440,228 @@ func (c *Context) String(status int, format string, values ...any) error {
 	})
 }
 
+// Render 使用 r 输出响应，status 小于 0 时不修改状态码
 func (c *Context) Render(status int, r render.Render) error {
-	err := r.Render(c.W)
-	if http.StatusOK != status {
+	//状态码要在写 body 之前设置，否则不会生效
+	if status > 0 {
 		c.W.WriteHeader(status)
 	}
-
-	return err
+	return r.Render(c.W)
 }
 
+// DealJson 解析 JSON 参数，IsValidate 为 true 时检查 must:"required" 的字段是否存在
 func (c *Context) DealJson(data any) error {
-	body := c.R.Body
-	if c.R == nil || body == nil {
+	if c.R == nil || c.R.Body == nil {
 		return errors.New("invalid request")
 	}
-	decoder := json.NewDecoder(body)
+	if c.IsValidate {
+		//解析的同时检查，只读取和解析一次 body
+		if err := binding.DecodeJSON(c.R.Body, data, c.DisallowUnknownFields); err != nil {
+			return err
+		}
+		return c.validate(data)
+	}
+	decoder := codec.JSON.NewDecoder(c.R.Body)
 	if c.DisallowUnknownFields {
 		//有未知的字段报错
 		decoder.DisallowUnknownFields()
 	}
-
-	if c.IsValidate {
-		err := validateRequireParam(data, decoder)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := decoder.Decode(data)
-		if err != nil {
-			return err
-		}
+	if err := decoder.Decode(data); err != nil {
+		return err
 	}
+	return c.validate(data)
+}
 
-	return validate(data)
+// Bind 根据请求方式和 Content-Type 自动选择 Binding，解析失败时返回 400
+func (c *Context) Bind(obj any) error {
+	return c.BindWith(obj, c.defaultBinding())
+}
+func (c *Context) BindJSON(obj any) error {
+	return c.BindWith(obj, c.jsonBinding())
+}
+func (c *Context) BindXML(obj any) error {
+	return c.BindWith(obj, binding.XML)
+}
+func (c *Context) BindYAML(obj any) error {
+	return c.BindWith(obj, binding.YAML)
+}
+func (c *Context) BindTOML(obj any) error {
+	return c.BindWith(obj, binding.TOML)
+}
+func (c *Context) BindMsgPack(obj any) error {
+	return c.BindWith(obj, binding.MsgPack)
+}
+func (c *Context) BindProtoBuf(obj any) error {
+	return c.BindWith(obj, binding.ProtoBuf)
+}
+func (c *Context) BindQuery(obj any) error {
+	return c.BindWith(obj, binding.Query)
+}
 
+// BindForm 解析 query 和 body 中的表单参数，支持 user[name] 形式的嵌套结构体
+func (c *Context) BindForm(obj any) error {
+	return c.BindWith(obj, binding.Form)
+}
+func (c *Context) BindHeader(obj any) error {
+	return c.BindWith(obj, binding.Header)
+}
+func (c *Context) BindUri(obj any) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		c.bindError(err)
+		return err
+	}
+	return nil
 }
 
-type SliceValidationError []error
+// BindWith 使用指定的 Binding 解析，失败时交给错误处理返回 400
+func (c *Context) BindWith(obj any, b binding.Binding) error {
+	if err := c.ShouldBindWith(obj, b); err != nil {
+		c.bindError(err)
+		return err
+	}
+	return nil
+}
 
-func (err SliceValidationError) Error() string {
-	n := len(err)
-	switch n {
-	case 0:
-		return ""
-	default:
-		var b strings.Builder
-		if err[0] != nil {
-			fmt.Fprintf(&b, "[%d]: %s", 0, err[0].Error())
-		}
-		if n > 1 {
-			for i := 1; i < n; i++ {
-				if err[i] != nil {
-					b.WriteString("\n")
-					fmt.Fprintf(&b, "[%d]: %s", i, err[i].Error())
-				}
-			}
-		}
-		return b.String()
+// ShouldBind 和 Bind 一样自动选择 Binding，但是只返回错误，不写响应
+func (c *Context) ShouldBind(obj any) error {
+	return c.ShouldBindWith(obj, c.defaultBinding())
+}
+func (c *Context) ShouldBindJSON(obj any) error {
+	return c.ShouldBindWith(obj, c.jsonBinding())
+}
+func (c *Context) ShouldBindXML(obj any) error {
+	return c.ShouldBindWith(obj, binding.XML)
+}
+func (c *Context) ShouldBindYAML(obj any) error {
+	return c.ShouldBindWith(obj, binding.YAML)
+}
+func (c *Context) ShouldBindTOML(obj any) error {
+	return c.ShouldBindWith(obj, binding.TOML)
+}
+func (c *Context) ShouldBindMsgPack(obj any) error {
+	return c.ShouldBindWith(obj, binding.MsgPack)
+}
+func (c *Context) ShouldBindProtoBuf(obj any) error {
+	return c.ShouldBindWith(obj, binding.ProtoBuf)
+}
+func (c *Context) ShouldBindQuery(obj any) error {
+	return c.ShouldBindWith(obj, binding.Query)
+}
+func (c *Context) ShouldBindForm(obj any) error {
+	return c.ShouldBindWith(obj, binding.Form)
+}
+func (c *Context) ShouldBindHeader(obj any) error {
+	return c.ShouldBindWith(obj, binding.Header)
+}
+func (c *Context) ShouldBindUri(obj any) error {
+	m := make(map[string][]string, len(c.params))
+	for k, v := range c.params {
+		m[k] = []string{v}
 	}
+	if err := binding.Uri.BindUri(m, obj); err != nil {
+		return err
+	}
+	return c.validate(obj)
 }
 
-func validateRequireParam(data any, decoder *json.Decoder) error {
-	if data == nil {
-		return nil
+// ShouldBindWith 使用指定的 Binding 解析，解析完成后进行参数校验
+func (c *Context) ShouldBindWith(obj any, b binding.Binding) error {
+	if err := b.Bind(c.R, obj); err != nil {
+		return err
 	}
-	valueOf := reflect.ValueOf(data)
-	//判断是否为指针类型
-	if valueOf.Kind() != reflect.Pointer {
-		return errors.New("no ptr type")
-	}
-	t := valueOf.Elem().Interface()
-	of := reflect.ValueOf(t)
-	switch of.Kind() {
-	case reflect.Struct: //如果是结构体类型，
-		return checkParam(of, data, decoder)
-	case reflect.Slice, reflect.Array:
-		elem := of.Type().Elem()
-		if elem.Kind() == reflect.Struct {
-			return checkParamSlice(elem, data, decoder)
-		}
-	default:
-		err := decoder.Decode(data)
-		if err != nil {
-			return err
-		}
+	return c.validate(obj)
+}
+
+// ShouldBindBodyWith 缓存 body 之后再解析，适用于中间件（例如签名校验）和处理函数都需要读取 body 的场景
+func (c *Context) ShouldBindBodyWith(obj any, bb binding.BindingBody) error {
+	body, err := c.GetRawData()
+	if err != nil {
+		return err
+	}
+	if err := bb.BindBody(body, obj); err != nil {
+		return err
+	}
+	return c.validate(obj)
+}
+
+func (c *Context) defaultBinding() binding.Binding {
+	b := binding.Default(c.R.Method, c.ContentType())
+	if b == binding.JSON {
+		return c.jsonBinding()
 	}
-	return validate(data)
+	return b
 }
-func validate(data any) error {
-	return Validator.ValidateStruct(data)
+func (c *Context) jsonBinding() binding.Binding {
+	if c.DisallowUnknownFields {
+		return binding.JSONDisallowUnknownFields
+	}
+	return binding.JSON
 }
 
-func checkParamSlice(of reflect.Type, data any, decoder *json.Decoder) error {
-	mapData := make([]map[string]interface{}, 0)
-	_ = decoder.Decode(&mapData)         //解析为map 根据key 进行比对
-	for i := 0; i < of.NumField(); i++ { //判断map的值有没有对应的key
-		field := of.Field(i)
-		jsomTag := field.Tag.Get("json")
-		tag := field.Tag.Get("must")
-		for _, v := range mapData {
-			value := v[jsomTag]
-			if value == nil && tag == "required" {
-				return errors.New(fmt.Sprintf("filed [%s] is not exist", jsomTag))
-			}
-		}
+func (c *Context) bindError(err error) {
+	if he := c.requestError(err); he != nil {
+		c.HandleError(he)
+		return
+	}
+	c.HandleError(NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err))
+}
 
+// requestError 将请求参数相关的错误转换成 HTTPError：body 超过限制返回 413，
+// 校验失败返回 400 和翻译后的字段错误，参数转换失败和必填字段缺失返回 400，其他错误返回 nil
+func (c *Context) requestError(err error) *HTTPError {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge).SetInternal(err)
+	}
+	if fieldErrors := c.TranslateError(err); fieldErrors != nil {
+		return NewHTTPError(http.StatusBadRequest, fieldErrors).SetInternal(err)
+	}
+	var fieldError *binding.FieldError
+	var missingFieldsError binding.MissingFieldsError
+	if errors.As(err, &fieldError) || errors.As(err, &missingFieldsError) {
+		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
 	}
-	marshal, _ := json.Marshal(mapData)
-	_ = json.Unmarshal(marshal, data)
 	return nil
 }
-func checkParam(of reflect.Value, data any, decoder *json.Decoder) error {
-	mapData := make(map[string]interface{})
-	_ = decoder.Decode(&mapData)         //解析为map 根据key 进行比对
-	for i := 0; i < of.NumField(); i++ { //判断map的值有没有对应的key
-		field := of.Type().Field(i)
-		jsomTag := field.Tag.Get("json")
-		tag := field.Tag.Get("must")
-
-		value := mapData[jsomTag]
-		if value == nil && tag == "required" {
-			return errors.New(fmt.Sprintf("filed [%s] is not exist", jsomTag))
+
+// ContentType 返回请求的 Content-Type，不包含 charset 等参数
+func (c *Context) ContentType() string {
+	return filterFlags(c.R.Header.Get("Content-Type"))
+}
+
+// SliceElementError 切片中第 Index 个元素的校验错误
+type SliceElementError struct {
+	Index int
+	Err   error
+}
+
+func (e SliceElementError) Error() string {
+	return fmt.Sprintf("[%d]: %s", e.Index, e.Err.Error())
+}
+
+func (e SliceElementError) Unwrap() error {
+	return e.Err
+}
+
+// SliceValidationError 切片校验的错误，只包含校验失败的元素
+type SliceValidationError []SliceElementError
+
+func (err SliceValidationError) Error() string {
+	var b strings.Builder
+	for i, e := range err {
+		if i > 0 {
+			b.WriteString("\n")
 		}
+		b.WriteString(e.Error())
 	}
-	marshal, _ := json.Marshal(mapData)
-	_ = json.Unmarshal(marshal, data)
-	return nil
+	return b.String()
+}
+
+// TranslateError 根据 Accept-Language 翻译校验错误，不是校验错误时返回 nil
+func (c *Context) TranslateError(err error) FieldErrors {
+	return c.engine.Validator().Translate(err, parseAcceptLanguage(c.R.Header.Get("Accept-Language"))...)
+}
+
+func (c *Context) validate(data any) error {
+	return c.engine.Validator().ValidateStruct(data)
 }
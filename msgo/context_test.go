@@ -0,0 +1,245 @@
+package msgo
+
+import (
+	"github.com/mis403/msgo/binding"
+	"github.com/mis403/msgo/render"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextJSONP(t *testing.T) {
+	engine := NewEngine()
+	engine.Group("t").HandleE(http.MethodGet, "/jsonp", func(ctx *Context) error {
+		return ctx.JSONP(http.StatusOK, map[string]int{"a": 1})
+	})
+	tests := []struct {
+		callback string
+		code     int
+		body     string
+	}{
+		{"", http.StatusOK, `{"a":1}`},
+		{"cb", http.StatusOK, `cb({"a":1});`},
+		{"alert(document.cookie)//", http.StatusBadRequest, `{"code":400,"msg":"invalid callback"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/jsonp?callback="+url.QueryEscape(tt.callback), nil))
+		if w.Code != tt.code || strings.TrimRight(w.Body.String(), "\n") != tt.body {
+			t.Errorf("callback %q: %d %q, want %d %q", tt.callback, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestContextDealJson(t *testing.T) {
+	type user struct {
+		Name string `json:"name" must:"required"`
+		Age  int    `json:"age"`
+	}
+	tests := []struct {
+		body       string
+		isValidate bool
+		wantErr    bool
+	}{
+		{`{"name":"a","age":1}`, true, false},
+		{`{"age":1}`, true, true},
+		{`{"age":1}`, false, false},
+		{`{"name":"a","other":1}`, true, true},
+	}
+	for _, tt := range tests {
+		var err error
+		engine := NewEngine()
+		engine.Group("t").Post("/user", func(ctx *Context) {
+			ctx.IsValidate = tt.isValidate
+			ctx.DisallowUnknownFields = true
+			var u user
+			err = ctx.DealJson(&u)
+		})
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/t/user", strings.NewReader(tt.body)))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s validate=%v: err = %v", tt.body, tt.isValidate, err)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	engine := NewEngine()
+	engine.MaxBodySize = 16
+	bind := func(ctx *Context) error {
+		var v map[string]string
+		if err := ctx.ShouldBindJSON(&v); err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, v["a"])
+	}
+	g := engine.Group("t")
+	g.HandleE(http.MethodPost, "/engine", bind)
+	//路由级别的限制覆盖路由组和 Engine 的设置
+	g.HandleE(http.MethodPost, "/route", bind, BodyLimit(64))
+	g.HandleE(http.MethodPost, "/unlimited", bind, BodyLimit(-1))
+	//Bind 出错时直接写出 413
+	g.Post("/bind", func(ctx *Context) {
+		var v map[string]string
+		if ctx.BindJSON(&v) == nil {
+			_ = ctx.String(http.StatusOK, v["a"])
+		}
+	})
+	small := engine.Group("small")
+	small.SetMaxBodySize(4)
+	small.HandleE(http.MethodPost, "/group", bind)
+	large := engine.Group("large")
+	large.SetMaxBodySize(-1)
+	large.HandleE(http.MethodPost, "/group", bind)
+
+	short := `{"a":"b"}`
+	long := `{"a":"` + strings.Repeat("x", 32) + `"}`
+	tests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/t/engine", short, http.StatusOK},
+		{"/t/engine", long, http.StatusRequestEntityTooLarge},
+		{"/t/bind", long, http.StatusRequestEntityTooLarge},
+		{"/t/route", long, http.StatusOK},
+		{"/t/route", `{"a":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge},
+		{"/t/unlimited", long, http.StatusOK},
+		{"/small/group", short, http.StatusRequestEntityTooLarge},
+		{"/large/group", long, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Errorf("%s %d bytes: %d %s, want %d", tt.path, len(tt.body), w.Code, w.Body.String(), tt.code)
+		}
+		if tt.code == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), `"code":413`) {
+			t.Errorf("%s: body = %s", tt.path, w.Body.String())
+		}
+	}
+}
+
+func TestShouldBindBodyWith(t *testing.T) {
+	engine := NewEngine()
+	engine.MaxBodySize = 32
+	//中间件先读取 body，处理函数仍然可以解析
+	readBody := func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if body, err := ctx.GetRawData(); err != nil || len(body) == 0 {
+				_ = ctx.String(http.StatusBadRequest, "read body failed")
+				return
+			}
+			next(ctx)
+		}
+	}
+	engine.Group("t").HandleE(http.MethodPost, "/bind", func(ctx *Context) error {
+		var a, b struct {
+			Name string `json:"name"`
+		}
+		if err := ctx.ShouldBindBodyWith(&a, binding.JSON); err != nil {
+			return err
+		}
+		if err := ctx.ShouldBindBodyWith(&b, binding.JSON); err != nil {
+			return err
+		}
+		body, err := io.ReadAll(ctx.R.Body)
+		if err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, a.Name+" "+b.Name+" "+string(body))
+	}, readBody)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/t/bind", strings.NewReader(`{"name":"a"}`)))
+	if w.Code != http.StatusOK || w.Body.String() != `a a {"name":"a"}` {
+		t.Fatalf("%d %q", w.Code, w.Body.String())
+	}
+
+	//GetRawData 超过限制时返回 413
+	w = httptest.NewRecorder()
+	engine.Group("raw").HandleE(http.MethodPost, "/bind", func(ctx *Context) error {
+		_, err := ctx.GetRawData()
+		return err
+	})
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/raw/bind", strings.NewReader(strings.Repeat("x", 64))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized raw body: %d %q", w.Code, w.Body.String())
+	}
+}
+
+type roundTripItem struct {
+	Name string   `yaml:"name" toml:"name" msgpack:"name"`
+	Tags []string `yaml:"tags" toml:"tags" msgpack:"tags"`
+}
+
+func TestRenderBindRoundTrip(t *testing.T) {
+	tests := []struct {
+		contentType string
+		respType    string
+		render      func(data any) render.Render
+		newObj      func() any
+		want        any
+	}{
+		{binding.MIMEYAML, "application/x-yaml; charset=utf-8", func(data any) render.Render { return &render.YAML{Data: data} },
+			func() any { return &roundTripItem{} }, &roundTripItem{Name: "msgo", Tags: []string{"a", "b"}}},
+		{binding.MIMETOML, "application/toml; charset=utf-8", func(data any) render.Render { return &render.TOML{Data: data} },
+			func() any { return &roundTripItem{} }, &roundTripItem{Name: "msgo", Tags: []string{"a", "b"}}},
+		{binding.MIMEMSGPACK, binding.MIMEMSGPACK, func(data any) render.Render { return &render.MsgPack{Data: data} },
+			func() any { return &roundTripItem{} }, &roundTripItem{Name: "msgo", Tags: []string{"a", "b"}}},
+		{binding.MIMEPROTOBUF, binding.MIMEPROTOBUF, func(data any) render.Render { return &render.ProtoBuf{Data: data} },
+			func() any { return &wrapperspb.StringValue{} }, wrapperspb.String("msgo")},
+	}
+	for _, tt := range tests {
+		engine := NewEngine()
+		//按照 Content-Type 解析请求，再用相同的格式返回
+		engine.Group("t").HandleE(http.MethodPost, "/echo", func(ctx *Context) error {
+			obj := tt.newObj()
+			if err := ctx.ShouldBind(obj); err != nil {
+				return err
+			}
+			return ctx.Render(http.StatusOK, tt.render(obj))
+		})
+		body := httptest.NewRecorder()
+		if err := tt.render(tt.want).Render(body); err != nil {
+			t.Fatalf("%s: %v", tt.contentType, err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/t/echo", body.Body)
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.respType {
+			t.Errorf("%s: %d %q %q", tt.contentType, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+			continue
+		}
+		got := tt.newObj()
+		b := binding.Default(http.MethodPost, tt.contentType).(binding.BindingBody)
+		if err := b.BindBody(w.Body.Bytes(), got); err != nil {
+			t.Errorf("%s: %v", tt.contentType, err)
+			continue
+		}
+		if m, ok := got.(proto.Message); ok {
+			if !proto.Equal(m, tt.want.(proto.Message)) {
+				t.Errorf("%s: got %v, want %v", tt.contentType, got, tt.want)
+			}
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.contentType, got, tt.want)
+		}
+	}
+
+	//ProtoBuf 的数据必须是 proto.Message
+	engine := NewEngine()
+	engine.Group("t").HandleE(http.MethodGet, "/pb", func(ctx *Context) error {
+		return ctx.ProtoBuf(http.StatusOK, map[string]int{"a": 1})
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/pb", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("non-proto data: %d %q", w.Code, w.Body.String())
+	}
+}
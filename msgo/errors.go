@@ -0,0 +1,80 @@
+package msgo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ErrHandlerFunc 是返回 error 的处理函数，返回的错误交给 Engine.ErrorHandler 统一处理
+type ErrHandlerFunc func(ctx *Context) error
+
+// ErrorHandlerFunc 统一错误处理函数，负责把错误转换成状态码和响应内容
+type ErrorHandlerFunc func(ctx *Context, err error)
+
+// HTTPError 携带状态码的错误，Message 会渲染给客户端，Internal 只用于日志
+type HTTPError struct {
+	Code     int
+	Message  any
+	Internal error
+}
+
+// NewHTTPError 创建一个 HTTPError，不传 message 时使用状态码对应的默认描述
+func NewHTTPError(code int, message ...any) *HTTPError {
+	he := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		he.Message = message[0]
+	}
+	return he
+}
+
+func (he *HTTPError) Error() string {
+	if he.Internal == nil {
+		return fmt.Sprintf("code=%d, message=%v", he.Code, he.Message)
+	}
+	return fmt.Sprintf("code=%d, message=%v, internal=%v", he.Code, he.Message, he.Internal)
+}
+
+// SetInternal 设置内部错误
+func (he *HTTPError) SetInternal(err error) *HTTPError {
+	he.Internal = err
+	return he
+}
+
+func (he *HTTPError) Unwrap() error {
+	return he.Internal
+}
+
+// WrapE 将返回 error 的处理函数转换成普通的 HandlerFunc
+func WrapE(handlerFunc ErrHandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		if err := handlerFunc(ctx); err != nil {
+			ctx.HandleError(err)
+		}
+	}
+}
+
+// DefaultErrorHandler 默认的错误处理：HTTPError 使用自身的状态码，请求 body 超过限制返回 413，
+// 参数校验失败返回 400，其他错误统一返回 500
+func DefaultErrorHandler(ctx *Context, err error) {
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		if he = ctx.requestError(err); he == nil {
+			he = NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+	}
+	if he.Code >= http.StatusInternalServerError {
+		log.Println(err)
+	}
+	//响应已经写出，状态码无法再修改
+	if ctx.Written() {
+		return
+	}
+	if err := ctx.JSON(he.Code, map[string]any{
+		"code": he.Code,
+		"msg":  he.Message,
+	}); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,172 @@
+package msgo
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// FileOptions FileFromFS 和 Static 的选项
+type FileOptions struct {
+	// Index 请求目录时返回的文件，默认为 index.html，目录中没有这个文件时返回 404，不会列出目录
+	Index string
+	// SPAFallback 文件不存在并且请求的路径没有扩展名时返回根目录的 Index，用于前端路由的单页应用
+	SPAFallback bool
+	// ShowDotFiles 为 false 时路径中任意一段以 . 开头都返回 404，例如 .git、.env
+	ShowDotFiles bool
+	// ContentType 为空时根据扩展名或者文件内容推断
+	ContentType string
+	// Disposition 为 inline 或者 attachment 时设置 Content-Disposition，为空时不设置
+	Disposition string
+	// Filename Content-Disposition 中的文件名，为空时使用文件本身的名字
+	Filename string
+}
+
+// FileAttachment 将本地文件 filepath 作为附件下载，filename 为浏览器保存时使用的文件名。
+// 文件不存在时返回 404 的 HTTPError，不写出响应，在 HandleE 中直接返回即可
+func (c *Context) FileAttachment(filepath, filename string) error {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return fileError(err)
+	}
+	if stat.IsDir() {
+		return NewHTTPError(http.StatusNotFound)
+	}
+	c.setContentDisposition("attachment", filename)
+	c.ServeContent(stat.Name(), stat.ModTime(), f)
+	return nil
+}
+
+// FileFromFS 输出文件系统 fsys 中的 name 文件，name 是相对于 fsys 根目录的路径，不会修改请求的 URL。
+// 支持 Range 和条件请求；文件不存在、是隐藏文件时返回 404 的 HTTPError，没有权限时返回 403。
+// 出错时不写出响应，在 HandleE 中直接返回，由 HandleError 统一处理，例如
+//
+//	g.HandleE(http.MethodGet, "/docs/**", func(ctx *msgo.Context) error {
+//		return ctx.FileFromFS(ctx.Param("**"), http.Dir("docs"))
+//	})
+func (c *Context) FileFromFS(name string, fsys http.FileSystem, opts ...FileOptions) error {
+	var opt FileOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Index == "" {
+		opt.Index = "index.html"
+	}
+	name = path.Clean("/" + name)
+	if !opt.ShowDotFiles && hasDotSegment(name) {
+		return NewHTTPError(http.StatusNotFound)
+	}
+	f, stat, err := openFile(fsys, name, opt.Index)
+	if errors.Is(err, fs.ErrNotExist) && opt.SPAFallback && path.Ext(name) == "" {
+		f, stat, err = openFile(fsys, "/"+opt.Index, opt.Index)
+	}
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+
+	if opt.ContentType != "" {
+		c.W.Header().Set("Content-Type", opt.ContentType)
+	}
+	if opt.Disposition != "" {
+		filename := opt.Filename
+		if filename == "" {
+			filename = stat.Name()
+		}
+		c.setContentDisposition(opt.Disposition, filename)
+	}
+	c.ServeContent(stat.Name(), stat.ModTime(), f)
+	return nil
+}
+
+// FileFromIOFS 和 FileFromFS 相同，fsys 可以是 embed.FS、os.DirFS 等 fs.FS
+func (c *Context) FileFromIOFS(name string, fsys fs.FS, opts ...FileOptions) error {
+	return c.FileFromFS(name, http.FS(fsys), opts...)
+}
+
+// Static 将 prefix 下的请求映射到 fsys 中的文件，例如 Static("/assets", http.Dir("public"))
+// 使用 public/css/app.css 响应 /assets/css/app.css
+func (r *routerGroup) Static(prefix string, fsys http.FileSystem, opts ...FileOptions) {
+	handler := func(ctx *Context) error {
+		return ctx.FileFromFS(ctx.Param("**"), fsys, opts...)
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	//** 至少匹配一段路径，prefix 本身单独注册，返回根目录的 Index
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		if prefix != "" {
+			r.HandleE(method, prefix, handler)
+		}
+		r.HandleE(method, prefix+"/**", handler)
+	}
+}
+
+// openFile 打开 name，是目录时打开目录中的 index
+func openFile(fsys http.FileSystem, name, index string) (http.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if !stat.IsDir() {
+		return f, stat, nil
+	}
+	f.Close()
+	f, err = fsys.Open(path.Join(name, index))
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err = f.Stat()
+	if err == nil && stat.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, stat, nil
+}
+
+// hasDotSegment 判断路径中是否有以 . 开头的部分
+func hasDotSegment(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// fileError 不存在返回 404，没有权限返回 403，其他错误返回 500，不把路径暴露给客户端
+func fileError(err error) *HTTPError {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NewHTTPError(http.StatusNotFound).SetInternal(err)
+	case errors.Is(err, fs.ErrPermission):
+		return NewHTTPError(http.StatusForbidden).SetInternal(err)
+	default:
+		return NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+}
+
+// setContentDisposition 非 ASCII 的文件名按照 RFC 6266 使用 filename* 编码
+func (c *Context) setContentDisposition(disposition, filename string) {
+	if isASCII(filename) {
+		filename = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(filename)
+		c.W.Header().Set("Content-Disposition", disposition+`; filename="`+filename+`"`)
+	} else {
+		c.W.Header().Set("Content-Disposition", disposition+`; filename*=UTF-8''`+url.PathEscape(filename))
+	}
+}
@@ -0,0 +1,144 @@
+package msgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newStaticEngine(opts ...FileOptions) *Engine {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("home")},
+		"css/app.css":     {Data: []byte("body{}")},
+		"docs/index.html": {Data: []byte("docs")},
+		"empty/a.txt":     {Data: []byte("a")},
+		".env":            {Data: []byte("SECRET=1")},
+		"css/.hidden.css": {Data: []byte("hidden")},
+	}
+	engine := NewEngine()
+	engine.Group("t").Static("/assets/", http.FS(fsys), opts...)
+	return engine
+}
+
+func TestStatic(t *testing.T) {
+	engine := newStaticEngine()
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/t/assets", http.StatusOK, "home"},
+		{"/t/assets/", http.StatusOK, "home"},
+		{"/t/assets/css/app.css", http.StatusOK, "body{}"},
+		{"/t/assets/docs", http.StatusOK, "docs"},
+		{"/t/assets/docs/", http.StatusOK, "docs"},
+		{"/t/assets/empty", http.StatusNotFound, ""},
+		{"/t/assets/missing.css", http.StatusNotFound, ""},
+		{"/t/assets/.env", http.StatusNotFound, ""},
+		{"/t/assets/css/.hidden.css", http.StatusNotFound, ""},
+		{"/t/assets/css/../../.env", http.StatusNotFound, ""},
+		{"/t/assets/app", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != tt.code || (tt.code == http.StatusOK && w.Body.String() != tt.body) {
+			t.Errorf("%s: %d %q, want %d %q", tt.target, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		//错误只处理一次，只输出一个错误响应
+		if tt.code != http.StatusOK && strings.Count(w.Body.String(), "{") > 1 {
+			t.Errorf("%s: error written more than once: %q", tt.target, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/t/assets/css/app.css", nil)
+	r.Header.Set("Range", "bytes=0-3")
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "body" {
+		t.Fatalf("range: %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/t/assets/css/app.css", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "6" {
+		t.Fatalf("HEAD: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestStaticOptions(t *testing.T) {
+	engine := newStaticEngine(FileOptions{SPAFallback: true, ShowDotFiles: true, Disposition: "attachment"})
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/t/assets/app/settings", http.StatusOK, "home"},
+		{"/t/assets/missing.css", http.StatusNotFound, ""},
+		{"/t/assets/.env", http.StatusOK, "SECRET=1"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != tt.code || (tt.code == http.StatusOK && w.Body.String() != tt.body) {
+			t.Errorf("%s: %d %q, want %d %q", tt.target, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/assets/css/app.css", nil))
+	if w.Header().Get("Content-Disposition") != `attachment; filename="app.css"` {
+		t.Fatalf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+}
+
+func TestFileFromFSReturnsError(t *testing.T) {
+	var err error
+	engine := NewEngine()
+	engine.Group("t").Get("/file", func(ctx *Context) {
+		err = ctx.FileFromFS("missing.txt", http.Dir(t.TempDir()))
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/file", nil))
+	expectHTTPError(t, err, http.StatusNotFound)
+	//FileFromFS 不写出响应，由调用方决定如何处理错误
+	if w.Body.Len() != 0 {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+func TestFileAttachment(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.txt")
+	if err := os.WriteFile(file, []byte("report"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine()
+	g := engine.Group("t")
+	g.HandleE(http.MethodGet, "/report", func(ctx *Context) error {
+		return ctx.FileAttachment(file, "报告.txt")
+	})
+	g.HandleE(http.MethodGet, "/missing", func(ctx *Context) error {
+		return ctx.FileAttachment(filepath.Join(dir, "missing.txt"), "a.txt")
+	})
+	g.HandleE(http.MethodGet, "/dir", func(ctx *Context) error {
+		return ctx.FileAttachment(dir, "a.txt")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/report", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "report" ||
+		w.Header().Get("Content-Disposition") != "attachment; filename*=UTF-8''%E6%8A%A5%E5%91%8A.txt" {
+		t.Fatalf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	for _, target := range []string{"/t/missing", "/t/dir"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound || w.Header().Get("Content-Disposition") != "" {
+			t.Fatalf("%s: %d %v", target, w.Code, w.Header())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package msgo
+
+import (
+	"log"
+	"os"
+)
+
+// EnvMsgoMode 通过环境变量设置运行模式
+const EnvMsgoMode = "MSGO_MODE"
+
+const (
+	// DebugMode 开发模式，模板文件修改后自动重新加载
+	DebugMode = "debug"
+	// ReleaseMode 生产模式，模板只在启动时解析一次
+	ReleaseMode = "release"
+)
+
+var msgoMode = ReleaseMode
+
+func init() {
+	SetMode(os.Getenv(EnvMsgoMode))
+}
+
+// SetMode 设置运行模式，为空时使用 ReleaseMode，需要在加载模板之前调用。
+// 未知的模式记录日志并使用 ReleaseMode，不会因为环境变量写错导致程序无法启动
+func SetMode(mode string) {
+	switch mode {
+	case "", ReleaseMode:
+		msgoMode = ReleaseMode
+	case DebugMode:
+		msgoMode = DebugMode
+	default:
+		log.Printf("msgo: unknown mode %q (available modes: debug, release), using release", mode)
+		msgoMode = ReleaseMode
+	}
+}
+
+// Mode 返回当前的运行模式
+func Mode() string {
+	return msgoMode
+}
+
+// IsDebugging 是否为开发模式
+func IsDebugging() bool {
+	return msgoMode == DebugMode
+}
@@ -0,0 +1,102 @@
+package msgo
+
+import (
+	"github.com/mis403/msgo/render"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Mode())
+	tests := []struct {
+		mode, want string
+	}{
+		{"", ReleaseMode},
+		{DebugMode, DebugMode},
+		{ReleaseMode, ReleaseMode},
+		//未知的模式不 panic，使用 ReleaseMode
+		{"production", ReleaseMode},
+	}
+	for _, tt := range tests {
+		SetMode(tt.mode)
+		if Mode() != tt.want {
+			t.Errorf("SetMode(%q): Mode() = %q, want %q", tt.mode, Mode(), tt.want)
+		}
+	}
+}
+
+func TestLoadTemplateMode(t *testing.T) {
+	defer SetMode(Mode())
+	file := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(file, []byte(`{{.}}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	SetMode(ReleaseMode)
+	engine := NewEngine()
+	engine.LoadTemplateFiles(file)
+	if _, ok := engine.HTMLRender.(render.HTMLProduction); !ok {
+		t.Fatalf("release mode HTMLRender = %T, want render.HTMLProduction", engine.HTMLRender)
+	}
+
+	SetMode(DebugMode)
+	engine = NewEngine()
+	engine.LoadTemplateFiles(file)
+	if _, ok := engine.HTMLRender.(*render.HTMLDebug); !ok {
+		t.Fatalf("debug mode HTMLRender = %T, want *render.HTMLDebug", engine.HTMLRender)
+	}
+}
+
+func TestDebugTemplateReload(t *testing.T) {
+	defer SetMode(Mode())
+	defer func(interval time.Duration) { templatePollInterval = interval }(templatePollInterval)
+	templatePollInterval = 0
+	file := filepath.Join(t.TempDir(), "index.html")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(file, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+		//文件系统的时间精度可能较低，显式设置修改时间
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write(`v1 {{.}}`, now)
+
+	SetMode(DebugMode)
+	engine := NewEngine()
+	engine.LoadTemplateFiles(file)
+	get := templateRenderer(engine, "a")
+	if code, body := get("index.html"); code != http.StatusOK || body != "v1 a" {
+		t.Fatalf("before edit: %d %q", code, body)
+	}
+
+	write(`v2 {{.}}`, now.Add(time.Second))
+	if code, body := get("index.html"); code != http.StatusOK || body != "v2 a" {
+		t.Fatalf("after edit: %d %q", code, body)
+	}
+
+	//修改后的模板有错误时返回 500，修复之后恢复
+	write(`{{.`, now.Add(2*time.Second))
+	if code, _ := get("index.html"); code != http.StatusInternalServerError {
+		t.Fatalf("broken template: %d", code)
+	}
+	write(`v3 {{.}}`, now.Add(3*time.Second))
+	if code, body := get("index.html"); code != http.StatusOK || body != "v3 a" {
+		t.Fatalf("after fix: %d %q", code, body)
+	}
+
+	//生产模式下不重新加载
+	SetMode(ReleaseMode)
+	engine = NewEngine()
+	engine.LoadTemplateFiles(file)
+	get = templateRenderer(engine, "a")
+	write(`v4 {{.}}`, now.Add(4*time.Second))
+	if code, body := get("index.html"); code != http.StatusOK || body != "v3 a" {
+		t.Fatalf("release mode: %d %q", code, body)
+	}
+}
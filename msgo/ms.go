@@ -3,10 +3,13 @@ package msgo
 import (
 	"fmt"
 	"github.com/mis403/msgo/render"
+	"github.com/mis403/msgo/websocket"
 	"html/template"
 	"log"
 	"net/http"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
 // 定义一个常量表示任意请求方法
@@ -29,14 +32,26 @@ type routerGroup struct {
 	handlerMethodMap   map[string][]string                    // 请求路径对应的允许的请求方法映射
 	treeNode           *treeNode
 	middlewares        []MiddlewareFunc
+	maxBodySize        int64 // 请求 body 的最大字节数，0 表示使用 Engine.MaxBodySize
 }
 
 func (r *routerGroup) MiddlewareHandler(middlewareFunc ...MiddlewareFunc) {
 	r.middlewares = append(r.middlewares, middlewareFunc...)
 }
 
+// SetMaxBodySize 设置路由组的请求 body 大小限制，小于 0 表示不限制
+func (r *routerGroup) SetMaxBodySize(size int64) {
+	r.maxBodySize = size
+}
+
 // name: routerName method: requestType
 func (r *routerGroup) methodHandler(handlerFunc HandlerFunc, name string, method string, ctx *Context) {
+	//路由组的限制优先于 Engine 的限制，路由级别的限制通过 BodyLimit 中间件设置
+	maxBodySize := r.maxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = ctx.engine.MaxBodySize
+	}
+	ctx.setBodyLimit(maxBodySize)
 	//前置中间件
 	if r.middlewares != nil {
 		for _, middleware := range r.middlewares {
@@ -75,23 +90,62 @@ func (r *router) Group(name string) *routerGroup {
 // Engine 结构体表示一个引擎，包含一个路由器
 type Engine struct {
 	*router
-	funcMap    template.FuncMap
+	funcMap template.FuncMap
+	//模板引擎，可以替换为 render.TextTemplate 或者实现了 render.HTMLRender 的第三方模板引擎
 	HTMLRender render.HTMLRender
 	pool       sync.Pool
+	//统一错误处理，为空时使用 DefaultErrorHandler
+	ErrorHandler ErrorHandlerFunc
+	//请求 body 的最大字节数，超过时返回 413，0 表示不限制
+	MaxBodySize      int64
+	validator        StructValidator
+	secureJSONPrefix string
+	//WebSocket 路由使用的 Upgrader，为空时使用默认配置
+	WebSocketUpgrader *websocket.Upgrader
+}
+
+// SecureJsonPrefix 设置 Context.SecureJSON 使用的前缀，默认为 while(1);
+func (e *Engine) SecureJsonPrefix(prefix string) {
+	e.secureJSONPrefix = prefix
+}
+
+// SetValidator 设置当前 Engine 使用的校验器，不影响其他 Engine
+func (e *Engine) SetValidator(validator StructValidator) {
+	e.validator = validator
+}
+
+// Validator 返回当前 Engine 使用的校验器，没有设置时使用全局的 Validator
+func (e *Engine) Validator() StructValidator {
+	if e.validator != nil {
+		return e.validator
+	}
+	return Validator
 }
 
 func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
 	e.funcMap = funcMap
 }
 
-// 加载模板
+// 加载模板，开发模式下模板文件修改后会在渲染时重新解析
 func (e *Engine) LoadTemplate(pattern string) {
-	t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
-	e.SetHtmlTemplate(t)
-
+	e.loadTemplate(func() (render.HTMLRender, error) {
+		t, err := template.New("").Funcs(e.templateFuncs()).ParseGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return render.HTMLProduction{Template: t}, nil
+	}, func() (map[string]time.Time, error) {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return fileModTimes(files)
+	})
 }
+
+// SetHtmlTemplate 直接使用已经解析好的模板，不会自动重新加载
 func (e *Engine) SetHtmlTemplate(template *template.Template) {
-	e.HTMLRender = render.HTMLRender{
+	e.HTMLRender = render.HTMLProduction{
 		Template: template,
 	}
 }
@@ -99,9 +153,13 @@ func (e *Engine) SetHtmlTemplate(template *template.Template) {
 // ServeHTTP 方法用于处理 HTTP 请求
 func (e *Engine) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	ctx := e.pool.Get().(*Context)
-	ctx.W = writer
+	ctx.writermem.reset(writer)
+	ctx.W = &ctx.writermem
 	ctx.R = request
-	e.httpRequestHandler(ctx, writer, request)
+	ctx.reset()
+	e.httpRequestHandler(ctx, ctx.W, request)
+	//处理函数只设置了状态码没有写 body 时，在这里写出响应头
+	ctx.writermem.WriteHeaderNow()
 	e.pool.Put(ctx)
 }
 
@@ -159,10 +217,16 @@ func (r *routerGroup) Head(name string, handlerFunc HandlerFunc, middlewareFunc
 	r.handle(name, http.MethodHead, handlerFunc, middlewareFunc...)
 }
 
+// HandleE 注册返回 error 的处理函数，method 可以是 ANY
+func (r *routerGroup) HandleE(method, name string, handlerFunc ErrHandlerFunc, middlewareFunc ...MiddlewareFunc) {
+	r.handle(name, method, WrapE(handlerFunc), middlewareFunc...)
+}
+
 // NewEngine 函数用于创建一个新的 Engine 实例
 func NewEngine() *Engine {
 	engine := &Engine{
-		router: &router{},
+		router:           &router{},
+		secureJSONPrefix: "while(1);",
 	}
 	engine.pool.New = func() any {
 		return engine.allocateContext()
@@ -197,6 +261,7 @@ func (e *Engine) httpRequestHandler(ctx *Context, writer http.ResponseWriter, re
 		routerName := SubStringLast(request.URL.Path, "/"+g.groupName)
 		node := g.treeNode.Get(routerName)
 		if node != nil && node.isEnd {
+			ctx.params = parseParams(node.routerName, routerName)
 
 			// 尝试获取对应请求方法的处理函数
 			handle, ok := g.handleFuncMap[node.routerName][ANY]
@@ -0,0 +1,153 @@
+package msgo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mis403/msgo/binding"
+	"github.com/mis403/msgo/render"
+	"net/http"
+	"strings"
+)
+
+// Offers Negotiate 可以返回的数据，每种格式没有单独提供数据时使用 Data
+type Offers struct {
+	// Offered 支持的 MIME 类型，按照优先级排列，为空时根据提供的数据推断
+	Offered []string
+	// HTMLName HTML 使用的模板名，为空时 HTML 必须是字符串，直接输出
+	HTMLName string
+	JSON     any
+	XML      any
+	HTML     any
+	YAML     any
+	TOML     any
+	MsgPack  any
+	ProtoBuf any
+	Data     any
+}
+
+// offered 没有指定 Offered 时，按照 JSON、XML、YAML、MsgPack、TOML、ProtoBuf、HTML 的顺序返回提供了数据的格式，
+// TOML 和 ProtoBuf 对数据有要求，只有单独提供了数据时才会使用
+func (o *Offers) offered() []string {
+	if len(o.Offered) > 0 {
+		return o.Offered
+	}
+	offered := make([]string, 0, 7)
+	if o.JSON != nil || o.Data != nil {
+		offered = append(offered, binding.MIMEJSON)
+	}
+	if o.XML != nil || o.Data != nil {
+		offered = append(offered, binding.MIMEXML)
+	}
+	if o.YAML != nil || o.Data != nil {
+		offered = append(offered, binding.MIMEYAML)
+	}
+	if o.MsgPack != nil || o.Data != nil {
+		offered = append(offered, binding.MIMEMSGPACK)
+	}
+	if o.TOML != nil {
+		offered = append(offered, binding.MIMETOML)
+	}
+	if o.ProtoBuf != nil {
+		offered = append(offered, binding.MIMEPROTOBUF)
+	}
+	if o.HTML != nil || o.HTMLName != "" {
+		offered = append(offered, binding.MIMEHTML)
+	}
+	return offered
+}
+
+func (o *Offers) data(data any) any {
+	if data != nil {
+		return data
+	}
+	return o.Data
+}
+
+// Negotiate 根据 Accept 请求头选择返回的格式，没有匹配的格式时返回 406 的 HTTPError，
+// 不写出响应，在 HandleE 中直接返回即可
+func (c *Context) Negotiate(status int, offers Offers) error {
+	format := c.NegotiateFormat(offers.offered()...)
+	switch format {
+	case binding.MIMEJSON:
+		return c.JSON(status, offers.data(offers.JSON))
+	case binding.MIMEXML, binding.MIMEXML2:
+		//application/xml 和 text/xml 按照协商的结果返回
+		return c.Render(status, &render.XML{Data: offers.data(offers.XML), ContentType: format + "; charset=utf-8"})
+	case binding.MIMEYAML, binding.MIMEYAML2:
+		return c.Render(status, &render.YAML{Data: offers.data(offers.YAML), ContentType: format + "; charset=utf-8"})
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		return c.MsgPack(status, offers.data(offers.MsgPack))
+	case binding.MIMETOML:
+		return c.TOML(status, offers.data(offers.TOML))
+	case binding.MIMEPROTOBUF:
+		return c.ProtoBuf(status, offers.data(offers.ProtoBuf))
+	case binding.MIMEHTML:
+		if offers.HTMLName == "" {
+			html, ok := offers.HTML.(string)
+			if !ok {
+				return fmt.Errorf("msgo: Offers.HTML must be a string when HTMLName is empty, got %T", offers.HTML)
+			}
+			return c.Render(status, &render.HTML{Data: html})
+		}
+		if c.engine.HTMLRender == nil {
+			return errors.New("msgo: no template loaded")
+		}
+		data, err := c.mergeTemplateData(offers.data(offers.HTML))
+		if err != nil {
+			return err
+		}
+		return c.Render(status, c.engine.HTMLRender.Instance(offers.HTMLName, data))
+	default:
+		return NewHTTPError(http.StatusNotAcceptable)
+	}
+}
+
+// NegotiateFormat 从 offered 中选出最符合 Accept 请求头的 MIME 类型，支持 q 值和 */*、text/* 这样的通配符。
+// q=0 表示不接受，例如 "*/*, application/json;q=0" 不会选择 JSON。
+// 没有 Accept 请求头时返回第一个，没有匹配时返回空字符串
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	accepted := parseAcceptValues(c.R.Header.Get("Accept"))
+	if len(accepted) == 0 {
+		return offered[0]
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		//offer 的 q 值由匹配它的最精确的 media range 决定
+		q, specificity := 0.0, -1
+		for _, accept := range accepted {
+			if s := matchMediaType(accept.value, offer); s > specificity {
+				q, specificity = accept.q, s
+			}
+		}
+		//最精确的 media range 的 q 为 0 时排除这个 offer，q 值相同时按照 offered 的顺序
+		if specificity >= 0 && q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// matchMediaType 判断 Accept 中的 media range 是否匹配 offer，
+// 返回匹配的精确程度：*/* 为 0，text/* 为 1，完全相同为 2，不匹配为 -1
+func matchMediaType(accept, offer string) int {
+	accept = strings.ToLower(filterFlags(accept))
+	offer = strings.ToLower(filterFlags(offer))
+	if accept == "*/*" || accept == "*" {
+		return 0
+	}
+	acceptType, acceptSubtype, _ := strings.Cut(accept, "/")
+	offerType, offerSubtype, _ := strings.Cut(offer, "/")
+	if acceptType != offerType {
+		return -1
+	}
+	if acceptSubtype == "*" {
+		return 1
+	}
+	if acceptSubtype == offerSubtype {
+		return 2
+	}
+	return -1
+}
@@ -0,0 +1,129 @@
+package msgo
+
+import (
+	"github.com/mis403/msgo/binding"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func negotiateFormat(accept string, offered ...string) string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	ctx := &Context{R: r}
+	return ctx.NegotiateFormat(offered...)
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEHTML}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", binding.MIMEJSON},
+		{"application/xml", binding.MIMEXML},
+		{"text/html;q=0.9, application/xml;q=0.8", binding.MIMEHTML},
+		{"text/*", binding.MIMEHTML},
+		{"*/*", binding.MIMEJSON},
+		{"image/png", ""},
+		//q=0 明确排除，不能再通过 */* 匹配
+		{"*/*, application/json;q=0", binding.MIMEXML},
+		{"*/*;q=0.1, application/json;q=0, application/xml;q=0", binding.MIMEHTML},
+		{"application/json;q=0", ""},
+		{"text/*;q=0, */*", binding.MIMEJSON},
+		//更精确的 media range 优先于通配符
+		{"application/*;q=0.2, application/xml;q=0.9, */*;q=0.5", binding.MIMEXML},
+		{"APPLICATION/XML", binding.MIMEXML},
+		{"application/xml; charset=utf-8; q=0.5, text/html; q=0.4", binding.MIMEXML},
+	}
+	for _, tt := range tests {
+		if got := negotiateFormat(tt.accept, offered...); got != tt.want {
+			t.Errorf("Accept %q: got %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestParseAccept(t *testing.T) {
+	got := parseAccept("en;q=0.5, zh-CN, fr;q=0, de;q=0.8")
+	if want := []string{"zh-CN", "de", "en"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseAccept = %v, want %v", got, want)
+	}
+	got = parseAcceptLanguage("zh-CN,zh;q=0.9,*;q=0.1")
+	if want := []string{"zh_cn", "zh", "zh"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseAcceptLanguage = %v, want %v", got, want)
+	}
+	values := parseAcceptValues("a;q=abc, b;q=-1, ,c")
+	if len(values) != 3 || values[0].value != "a" || values[0].q != 1 || values[2].value != "b" || values[2].q != 0 {
+		t.Fatalf("parseAcceptValues = %+v", values)
+	}
+}
+
+func TestNegotiateMsgPackContentType(t *testing.T) {
+	engine := NewEngine()
+	engine.Group("t").HandleE(http.MethodGet, "/data", func(ctx *Context) error {
+		return ctx.Negotiate(http.StatusOK, Offers{Data: map[string]int{"a": 1}})
+	})
+	for _, accept := range []string{binding.MIMEMSGPACK, "application/json;q=0.5, application/x-msgpack"} {
+		r := httptest.NewRequest(http.MethodGet, "/t/data", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if ct := w.Header().Get("Content-Type"); ct != binding.MIMEMSGPACK {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", accept, ct, binding.MIMEMSGPACK)
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/t/data", nil)
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("status = %d, want 406", w.Code)
+	}
+}
+
+type negotiateData struct {
+	A int `xml:"a" yaml:"a"`
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	engine := NewEngine()
+	g := engine.Group("t")
+	g.HandleE(http.MethodGet, "/data", func(ctx *Context) error {
+		return ctx.Negotiate(http.StatusOK, Offers{
+			Offered: []string{binding.MIMEXML, binding.MIMEXML2, binding.MIMEYAML, binding.MIMEYAML2, binding.MIMEHTML},
+			Data:    negotiateData{A: 1},
+			HTML:    "<p>a</p>",
+		})
+	})
+	//没有模板时 HTML 只能是字符串
+	g.HandleE(http.MethodGet, "/html", func(ctx *Context) error {
+		return ctx.Negotiate(http.StatusOK, Offers{HTML: map[string]int{"a": 1}})
+	})
+	tests := []struct {
+		path        string
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"/t/data", "application/xml", http.StatusOK, "application/xml; charset=utf-8", "<negotiateData><a>1</a></negotiateData>"},
+		{"/t/data", "text/xml", http.StatusOK, "text/xml; charset=utf-8", "<negotiateData><a>1</a></negotiateData>"},
+		{"/t/data", "application/yaml", http.StatusOK, "application/yaml; charset=utf-8", "a: 1"},
+		{"/t/data", "application/x-yaml", http.StatusOK, "application/x-yaml; charset=utf-8", "a: 1"},
+		{"/t/data", "text/html", http.StatusOK, "text/html; charset=utf-8", "<p>a</p>"},
+		{"/t/html", "text/html", http.StatusInternalServerError, "application/json; charset=utf-8", `"code":500`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.code || w.Header().Get("Content-Type") != tt.contentType || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s %s: %d %q %q", tt.path, tt.accept, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
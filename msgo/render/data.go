@@ -0,0 +1,18 @@
+package render
+
+import "net/http"
+
+// Data 输出任意字节，Content-Type 由调用方指定
+type Data struct {
+	ContentType string
+	Data        []byte
+}
+
+func (d *Data) Render(w http.ResponseWriter) error {
+	d.WriteContentType(w)
+	_, err := w.Write(d.Data)
+	return err
+}
+func (d *Data) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, d.ContentType)
+}
@@ -1,14 +1,135 @@
 package render
 
 import (
+	"fmt"
 	"github.com/mis403/msgo/internal/bytesconv"
 	"html/template"
 	"net/http"
+	"sync"
+	"time"
 )
 
-type HTMLRender struct {
+// HTMLRender 模板引擎，Context.Template 通过 Instance 取得渲染单个页面的 Render。
+// 使用第三方模板引擎时实现这个接口，并赋值给 Engine.HTMLRender 即可，例如：
+//
+//	type PongoRender struct{ set *pongo2.TemplateSet }
+//
+//	func (p *PongoRender) Instance(name string, data any) render.Render {
+//		return &PongoHTML{Template: pongo2.Must(p.set.FromCache(name)), Data: data}
+//	}
+type HTMLRender interface {
+	Instance(name string, data any) Render
+}
+
+// HTMLProduction 默认的 html/template 模板引擎，所有模板在同一个集合中
+type HTMLProduction struct {
 	Template *template.Template
 }
+
+func (r HTMLProduction) Instance(name string, data any) Render {
+	return &HTML{
+		Data:       data,
+		Name:       name,
+		Template:   r.Template,
+		IsTemplate: true,
+	}
+}
+
+// HTMLLayout 每个页面和布局、公共片段一起解析成单独的模板集合，页面之间互不影响
+type HTMLLayout struct {
+	Templates map[string]*template.Template
+	// Layout 布局模板名，为空时直接执行页面模板
+	Layout string
+}
+
+func (r HTMLLayout) Instance(name string, data any) Render {
+	h := &HTML{Data: data, Name: name, IsTemplate: true}
+	if t, ok := r.Templates[name]; ok {
+		h.Template = t
+		if r.Layout != "" {
+			h.Name = r.Layout
+		}
+	}
+	return h
+}
+
+// HTMLDebug 开发模式使用，渲染前轮询模板文件的修改时间，有变化时重新加载
+type HTMLDebug struct {
+	load     func() (HTMLRender, error)
+	watch    func() (map[string]time.Time, error)
+	interval time.Duration
+
+	mu        sync.Mutex
+	current   HTMLRender
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// NewHTMLDebug 立即加载一次模板，watch 返回模板文件的修改时间，interval 为两次检查之间的最小间隔
+func NewHTMLDebug(load func() (HTMLRender, error), watch func() (map[string]time.Time, error), interval time.Duration) (*HTMLDebug, error) {
+	current, err := load()
+	if err != nil {
+		return nil, err
+	}
+	modTimes, err := watch()
+	if err != nil {
+		return nil, err
+	}
+	return &HTMLDebug{
+		load:      load,
+		watch:     watch,
+		interval:  interval,
+		current:   current,
+		modTimes:  modTimes,
+		lastCheck: time.Now(),
+	}, nil
+}
+
+// Instance 重新加载失败时返回的 Render 只返回加载的错误
+func (r *HTMLDebug) Instance(name string, data any) Render {
+	current, err := r.reload()
+	if err != nil {
+		return &errorRender{err: err}
+	}
+	return current.Instance(name, data)
+}
+
+func (r *HTMLDebug) reload() (HTMLRender, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) < r.interval {
+		return r.current, nil
+	}
+	r.lastCheck = time.Now()
+	modTimes, err := r.watch()
+	if err != nil {
+		return nil, err
+	}
+	if sameModTimes(r.modTimes, modTimes) {
+		return r.current, nil
+	}
+	current, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current = current
+	r.modTimes = modTimes
+	return current, nil
+}
+
+// sameModTimes 文件的数量和修改时间都相同时认为模板没有变化
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, t := range a {
+		if other, ok := b[name]; !ok || !other.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
 type HTML struct {
 	Data       any
 	Name       string
@@ -19,6 +140,9 @@ type HTML struct {
 func (h *HTML) Render(w http.ResponseWriter) error {
 	h.WriteContentType(w)
 	if h.IsTemplate {
+		if h.Template == nil {
+			return fmt.Errorf("render: template %q is undefined", h.Name)
+		}
 		err := h.Template.ExecuteTemplate(w, h.Name, h.Data)
 		return err
 	}
@@ -28,3 +152,14 @@ func (h *HTML) Render(w http.ResponseWriter) error {
 func (h *HTML) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, "text/html; charset=utf-8")
 }
+
+// errorRender 不输出内容，直接返回错误
+type errorRender struct {
+	err error
+}
+
+func (e *errorRender) Render(http.ResponseWriter) error {
+	return e.err
+}
+
+func (e *errorRender) WriteContentType(http.ResponseWriter) {}
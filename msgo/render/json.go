@@ -1,17 +1,56 @@
 package render
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mis403/msgo/codec"
+	"github.com/mis403/msgo/internal/bytesconv"
 	"net/http"
+	"regexp"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	jsonContentType       = "application/json; charset=utf-8"
+	javaScriptContentType = "application/javascript; charset=utf-8"
 )
 
 type JSON struct {
 	Data any
 }
 
+// IndentedJSON 格式化输出的 JSON，方便调试
+type IndentedJSON struct {
+	Data any
+}
+
+// SecureJSON 数组类型的 JSON 前面加上 Prefix，防止 JSON 劫持
+type SecureJSON struct {
+	Prefix string
+	Data   any
+}
+
+// JsonpJSON 输出 callback(data); 形式的 JSONP
+type JsonpJSON struct {
+	Callback string
+	Data     any
+}
+
+// AsciiJSON 非 ASCII 字符转义为 \uXXXX
+type AsciiJSON struct {
+	Data any
+}
+
+// PureJSON 不转义 <、>、& 等 HTML 字符
+type PureJSON struct {
+	Data any
+}
+
 func (j *JSON) Render(w http.ResponseWriter) error {
 	j.WriteContentType(w)
-	jsonData, err := json.Marshal(j.Data)
+	jsonData, err := codec.JSON.Marshal(j.Data)
 
 	if err != nil {
 		return err
@@ -20,5 +59,105 @@ func (j *JSON) Render(w http.ResponseWriter) error {
 	return err
 }
 func (j *JSON) WriteContentType(w http.ResponseWriter) {
-	writeContentType(w, "text/json; charset=utf-8")
+	writeContentType(w, jsonContentType)
+}
+
+func (j *IndentedJSON) Render(w http.ResponseWriter) error {
+	j.WriteContentType(w)
+	jsonData, err := codec.JSON.MarshalIndent(j.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+func (j *IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func (j *SecureJSON) Render(w http.ResponseWriter) error {
+	j.WriteContentType(w)
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(jsonData, []byte("[")) && bytes.HasSuffix(jsonData, []byte("]")) {
+		if _, err = w.Write(bytesconv.StringToBytes(j.Prefix)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+func (j *SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func (j *JsonpJSON) Render(w http.ResponseWriter) error {
+	j.WriteContentType(w)
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	if j.Callback == "" {
+		_, err = w.Write(jsonData)
+		return err
+	}
+	//callback 来自请求参数，只允许合法的 JavaScript 标识符，转义不能阻止 alert(1)// 这样的注入
+	if !ValidJSONPCallback(j.Callback) {
+		return ErrInvalidCallback
+	}
+	_, err = fmt.Fprintf(w, "%s(%s);", j.Callback, jsonData)
+	return err
+}
+
+// ErrInvalidCallback JSONP 的回调函数名不合法
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+// jsonpCallbackPattern 标识符或者用 . 连接的标识符，例如 cb、jQuery123_456、app.handlers.cb
+var jsonpCallbackPattern = regexp.MustCompile(`^[A-Za-z_$][\w$]*(\.[A-Za-z_$][\w$]*)*$`)
+
+// ValidJSONPCallback 判断 callback 是否可以安全地作为 JSONP 的回调函数名
+func ValidJSONPCallback(callback string) bool {
+	return len(callback) <= 128 && jsonpCallbackPattern.MatchString(callback)
+}
+func (j *JsonpJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, javaScriptContentType)
+}
+
+func (j *AsciiJSON) Render(w http.ResponseWriter) error {
+	j.WriteContentType(w)
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	for len(jsonData) > 0 {
+		r, size := utf8.DecodeRune(jsonData)
+		if r < utf8.RuneSelf {
+			buffer.WriteByte(jsonData[0])
+		} else if r > 0xFFFF {
+			//超出基本平面的字符使用代理对表示
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&buffer, "\\u%04x\\u%04x", r1, r2)
+		} else {
+			fmt.Fprintf(&buffer, "\\u%04x", r)
+		}
+		jsonData = jsonData[size:]
+	}
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+func (j *AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func (j *PureJSON) Render(w http.ResponseWriter) error {
+	j.WriteContentType(w)
+	encoder := codec.JSON.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(j.Data)
+}
+func (j *PureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
 }
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidJSONPCallback(t *testing.T) {
+	valid := []string{"cb", "_cb", "$", "jQuery1830_1234", "app.handlers.cb", "$.cb"}
+	invalid := []string{"", "alert(document.cookie)//", "cb;alert(1)", "1cb", "a..b", "a.", ".a", "cb<script>", "a-b", "cb\n"}
+	for _, c := range valid {
+		if !ValidJSONPCallback(c) {
+			t.Errorf("ValidJSONPCallback(%q) = false", c)
+		}
+	}
+	for _, c := range invalid {
+		if ValidJSONPCallback(c) {
+			t.Errorf("ValidJSONPCallback(%q) = true", c)
+		}
+	}
+	long := make([]byte, 129)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if ValidJSONPCallback(string(long)) {
+		t.Error("callback longer than 128 bytes should be rejected")
+	}
+}
+
+func TestJsonpJSONRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (&JsonpJSON{Callback: "app.cb", Data: map[string]int{"a": 1}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != `app.cb({"a":1});` {
+		t.Fatalf("body = %q", got)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != javaScriptContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	if err := (&JsonpJSON{Callback: "alert(1)//", Data: 1}).Render(w); err != ErrInvalidCallback {
+		t.Fatalf("err = %v, want ErrInvalidCallback", err)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("body = %q, want empty", w.Body.String())
+	}
+}
@@ -0,0 +1,20 @@
+package render
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+type MsgPack struct {
+	Data any
+}
+
+func (m *MsgPack) Render(w http.ResponseWriter) error {
+	m.WriteContentType(w)
+	return msgpack.NewEncoder(w).Encode(m.Data)
+}
+
+// WriteContentType 和 binding.MIMEMSGPACK 相同，Negotiate 协商的类型和响应的 Content-Type 一致
+func (m *MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-msgpack")
+}
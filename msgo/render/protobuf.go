@@ -0,0 +1,28 @@
+package render
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+)
+
+type ProtoBuf struct {
+	Data any
+}
+
+func (p *ProtoBuf) Render(w http.ResponseWriter) error {
+	p.WriteContentType(w)
+	message, ok := p.Data.(proto.Message)
+	if !ok {
+		return errors.New("render: data is not a proto.Message")
+	}
+	bytes, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+func (p *ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-protobuf")
+}
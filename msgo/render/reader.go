@@ -0,0 +1,39 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Reader 将 Reader 中的内容输出到响应中，ContentLength 小于 0 时不设置 Content-Length
+type Reader struct {
+	ContentType   string
+	ContentLength int64
+	Reader        io.Reader
+	Headers       map[string]string
+}
+
+func (r *Reader) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	r.writeHeaders(w)
+	//直接设置到响应头上，Headers 可能是调用方共享的 map，不能修改
+	if r.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+func (r *Reader) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, r.ContentType)
+}
+
+// writeHeaders 写入额外的响应头，已经存在的不会被覆盖
+func (r *Reader) writeHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	for k, v := range r.Headers {
+		if header.Get(k) == "" {
+			header.Set(k, v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	headers := map[string]string{"Content-Disposition": `attachment; filename="a.txt"`, "X-Existing": "new"}
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Existing", "old")
+	r := &Reader{ContentType: "text/plain", ContentLength: 5, Reader: strings.NewReader("hello"), Headers: headers}
+	if err := r.Render(w); err != nil {
+		t.Fatal(err)
+	}
+	header := w.Header()
+	if w.Body.String() != "hello" || header.Get("Content-Length") != "5" || header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("got %q, headers %v", w.Body.String(), header)
+	}
+	if header.Get("Content-Disposition") == "" || header.Get("X-Existing") != "old" {
+		t.Fatalf("extra headers %v", header)
+	}
+	//调用方的 map 不能被修改
+	if len(headers) != 2 {
+		t.Fatalf("Headers modified: %v", headers)
+	}
+
+	w = httptest.NewRecorder()
+	r = &Reader{ContentLength: -1, Reader: strings.NewReader("hello")}
+	if err := r.Render(w); err != nil || w.Header().Get("Content-Length") != "" || r.Headers != nil {
+		t.Fatalf("unknown length: err %v, headers %v, Reader.Headers %v", err, w.Header(), r.Headers)
+	}
+}
+
+// TestReaderSharedHeaders 多个请求共用同一个 Headers 时不会出现并发写 map
+func TestReaderSharedHeaders(t *testing.T) {
+	headers := map[string]string{"Cache-Control": "no-cache"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := &Reader{ContentLength: 5, Reader: strings.NewReader("hello"), Headers: headers}
+			_ = r.Render(httptest.NewRecorder())
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"bytes"
+	"github.com/mis403/msgo/codec"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SSEvent Server-Sent Events 中的一个事件，Data 为 string 或 []byte 时原样输出，其他类型编码为 JSON
+type SSEvent struct {
+	Event   string
+	Id      string
+	Retry   uint // 客户端重连的间隔，单位毫秒
+	Comment string
+	Data    any
+}
+
+// 字段中不能出现换行，否则会被客户端当作新的字段
+var fieldReplacer = strings.NewReplacer("\n", "\\n", "\r", "\\r")
+
+func (s *SSEvent) Render(w http.ResponseWriter) error {
+	s.WriteContentType(w)
+	var buf bytes.Buffer
+	if s.Comment != "" {
+		writeLines(&buf, ": ", s.Comment)
+	}
+	if s.Id != "" {
+		buf.WriteString("id: " + fieldReplacer.Replace(s.Id) + "\n")
+	}
+	if s.Event != "" {
+		buf.WriteString("event: " + fieldReplacer.Replace(s.Event) + "\n")
+	}
+	if s.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatUint(uint64(s.Retry), 10) + "\n")
+	}
+	if s.Data != nil {
+		data, err := s.encodeData()
+		if err != nil {
+			return err
+		}
+		writeLines(&buf, "data: ", data)
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (s *SSEvent) encodeData() (string, error) {
+	switch data := s.Data.(type) {
+	case string:
+		return data, nil
+	case []byte:
+		return string(data), nil
+	default:
+		jsonData, err := codec.JSON.Marshal(data)
+		return string(jsonData), err
+	}
+}
+
+// writeLines 多行内容每一行都要加上前缀
+func writeLines(buf *bytes.Buffer, prefix, value string) {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	for _, line := range strings.Split(value, "\n") {
+		buf.WriteString(prefix + line + "\n")
+	}
+}
+
+func (s *SSEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	//关闭 nginx 的缓冲，事件可以及时推送到客户端
+	header.Set("X-Accel-Buffering", "no")
+}
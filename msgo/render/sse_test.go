@@ -0,0 +1,35 @@
+package render
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSEventRender(t *testing.T) {
+	tests := []struct {
+		event SSEvent
+		want  string
+	}{
+		{SSEvent{Data: "hello"}, "data: hello\n\n"},
+		{SSEvent{Event: "tick", Id: "1", Retry: 3000, Data: []byte("a")}, "id: 1\nevent: tick\nretry: 3000\ndata: a\n\n"},
+		//多行的数据和注释每一行都有前缀
+		{SSEvent{Comment: "a\nb", Data: "x\r\ny\nz"}, ": a\n: b\ndata: x\ndata: y\ndata: z\n\n"},
+		{SSEvent{Event: "a\nb", Id: "1\r2", Data: map[string]int{"n": 1}}, "id: 1\\r2\nevent: a\\nb\ndata: {\"n\":1}\n\n"},
+		{SSEvent{Comment: "keep-alive"}, ": keep-alive\n\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if err := tt.event.Render(w); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.event, got, tt.want)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream; charset=utf-8" || w.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("headers = %v", w.Header())
+		}
+	}
+	if err := (&SSEvent{Data: func() {}}).Render(httptest.NewRecorder()); err == nil {
+		t.Fatal("unencodable data should fail")
+	}
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// TextTemplate 使用 text/template 的模板引擎，不做 HTML 转义，用于纯文本、邮件等非 HTML 的输出
+type TextTemplate struct {
+	Template *template.Template
+	// ContentType 响应的 Content-Type，默认为 text/plain; charset=utf-8
+	ContentType string
+}
+
+func (r TextTemplate) Instance(name string, data any) Render {
+	return &Text{
+		Data:        data,
+		Name:        name,
+		Template:    r.Template,
+		ContentType: r.ContentType,
+	}
+}
+
+type Text struct {
+	Data        any
+	Name        string
+	Template    *template.Template
+	ContentType string
+}
+
+func (t *Text) Render(w http.ResponseWriter) error {
+	t.WriteContentType(w)
+	if t.Template == nil {
+		return fmt.Errorf("render: template %q is undefined", t.Name)
+	}
+	return t.Template.ExecuteTemplate(w, t.Name, t.Data)
+}
+
+func (t *Text) WriteContentType(w http.ResponseWriter) {
+	if t.ContentType == "" {
+		writeContentType(w, "text/plain; charset=utf-8")
+		return
+	}
+	writeContentType(w, t.ContentType)
+}
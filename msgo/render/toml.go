@@ -0,0 +1,23 @@
+package render
+
+import (
+	"github.com/pelletier/go-toml/v2"
+	"net/http"
+)
+
+type TOML struct {
+	Data any
+}
+
+func (t *TOML) Render(w http.ResponseWriter) error {
+	t.WriteContentType(w)
+	bytes, err := toml.Marshal(t.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+func (t *TOML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/toml; charset=utf-8")
+}
@@ -7,6 +7,8 @@ import (
 
 type XML struct {
 	Data any
+	// ContentType 为空时使用 text/xml; charset=utf-8，Negotiate 使用协商出的类型
+	ContentType string
 }
 
 func (x *XML) Render(w http.ResponseWriter) error {
@@ -15,5 +17,9 @@ func (x *XML) Render(w http.ResponseWriter) error {
 	return err
 }
 func (x *XML) WriteContentType(w http.ResponseWriter) {
+	if x.ContentType != "" {
+		writeContentType(w, x.ContentType)
+		return
+	}
 	writeContentType(w, "text/xml; charset=utf-8")
 }
@@ -0,0 +1,29 @@
+package render
+
+import (
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type YAML struct {
+	Data any
+	// ContentType 为空时使用 application/x-yaml; charset=utf-8，Negotiate 使用协商出的类型
+	ContentType string
+}
+
+func (y *YAML) Render(w http.ResponseWriter) error {
+	y.WriteContentType(w)
+	bytes, err := yaml.Marshal(y.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+func (y *YAML) WriteContentType(w http.ResponseWriter) {
+	if y.ContentType != "" {
+		writeContentType(w, y.ContentType)
+		return
+	}
+	writeContentType(w, "application/x-yaml; charset=utf-8")
+}
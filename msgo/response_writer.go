@@ -0,0 +1,106 @@
+package msgo
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+const (
+	noWritten     = -1
+	defaultStatus = http.StatusOK
+)
+
+// ResponseWriter 在 http.ResponseWriter 的基础上记录状态码和写出的字节数
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Hijacker
+	http.Flusher
+
+	// Status 返回当前响应的状态码
+	Status() int
+	// Size 返回已经写出的 body 字节数，未写出时为 -1
+	Size() int
+	// Written 判断响应头是否已经写出
+	Written() bool
+	// WriteHeaderNow 强制写出响应头
+	WriteHeaderNow()
+}
+
+// responseWriter 延迟写出响应头，直到第一次写 body 或者请求处理结束，
+// 这样在写出之前都可以修改状态码和响应头
+type responseWriter struct {
+	http.ResponseWriter
+	size   int
+	status int
+}
+
+var _ ResponseWriter = &responseWriter{}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.status = defaultStatus
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code {
+		if w.Written() {
+			return
+		}
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hijacker.Hijack()
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// responseWritten 判断 w 是否已经写出响应，w 没有实现 Written 时返回 false
+func responseWritten(w http.ResponseWriter) bool {
+	if rw, ok := w.(interface{ Written() bool }); ok {
+		return rw.Written()
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package msgo
+
+import (
+	"github.com/mis403/msgo/render"
+	"net/http"
+	"time"
+)
+
+// SSEvent 推送一个事件并立即刷新缓冲区
+func (c *Context) SSEvent(name string, data any) error {
+	return c.SSEventWith(render.SSEvent{Event: name, Data: data})
+}
+
+// SSEventWith 推送一个完整的事件，可以设置 Id、Retry 和 Comment
+func (c *Context) SSEventWith(event render.SSEvent) error {
+	if err := c.Render(-1, &event); err != nil {
+		return err
+	}
+	if flusher, ok := c.W.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// LastEventID 客户端重连时通过 Last-Event-ID 请求头带上收到的最后一个事件的 Id，用于断点续传
+func (c *Context) LastEventID() string {
+	return c.R.Header.Get("Last-Event-ID")
+}
+
+// SSEStream 依次推送 events 中的事件，直到 events 被关闭或者客户端断开连接，客户端断开时返回 true。
+// keepAlive 大于 0 时，超过 keepAlive 没有推送事件会发送注释保持连接，防止被代理服务器断开
+func (c *Context) SSEStream(events <-chan render.SSEvent, keepAlive time.Duration) bool {
+	clientGone := c.R.Context().Done()
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		ticker = time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	//先写出响应头，客户端可以尽早确认连接建立
+	c.SSEventWith(render.SSEvent{Comment: "ok"})
+	for {
+		select {
+		case <-clientGone:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.SSEventWith(event); err != nil {
+				return true
+			}
+			//推送了事件之后重新计算空闲时间
+			if ticker != nil {
+				ticker.Reset(keepAlive)
+			}
+		case <-tick:
+			if err := c.SSEventWith(render.SSEvent{Comment: "keep-alive"}); err != nil {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package msgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/mis403/msgo/render"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSSE 读取一个事件的所有行，不包含结尾的空行
+func readSSE(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v, got %q", err, lines)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func newSSEServer(t *testing.T, handler HandlerFunc) *httptest.Server {
+	t.Helper()
+	engine := NewEngine()
+	engine.Group("t").Get("/sse", handler)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSSEStream(t *testing.T) {
+	const keepAlive = 100 * time.Millisecond
+	events := make(chan render.SSEvent)
+	result := make(chan bool, 1)
+	server := newSSEServer(t, func(ctx *Context) {
+		result <- ctx.SSEStream(events, keepAlive)
+	})
+
+	resp, err := http.Get(server.URL + "/t/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	if got := readSSE(t, r); got != ": ok\n" {
+		t.Fatalf("first event = %q", got)
+	}
+
+	//持续推送事件时不发送 keep-alive
+	start := time.Now()
+	for i := 0; time.Since(start) < 2*keepAlive; i++ {
+		events <- render.SSEvent{Event: "tick", Data: "x"}
+		if got := readSSE(t, r); got != "event: tick\ndata: x\n" {
+			t.Fatalf("event %d = %q", i, got)
+		}
+		time.Sleep(keepAlive / 10)
+	}
+	//空闲之后发送 keep-alive
+	if got := readSSE(t, r); got != ": keep-alive\n" {
+		t.Fatalf("idle event = %q", got)
+	}
+
+	close(events)
+	if gone := <-result; gone {
+		t.Fatal("SSEStream reported client gone after events closed")
+	}
+}
+
+func TestSSEStreamClientGone(t *testing.T) {
+	events := make(chan render.SSEvent)
+	result := make(chan bool, 1)
+	server := newSSEServer(t, func(ctx *Context) {
+		result <- ctx.SSEStream(events, 0)
+	})
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"/t/sse", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := readSSE(t, bufio.NewReader(resp.Body)); got != ": ok\n" {
+		t.Fatalf("first event = %q", got)
+	}
+	cancel()
+	select {
+	case gone := <-result:
+		if !gone {
+			t.Fatal("SSEStream did not report client gone")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SSEStream did not return after client disconnected")
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+)
+
+// Memory 保存在内存中，用于测试
+type Memory struct {
+	BaseURL string
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+func NewMemory(baseURL string) *Memory {
+	return &Memory{BaseURL: baseURL, objects: make(map[string]*memoryObject)}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(readerWithContext(ctx, r))
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum(data)
+	obj := &memoryObject{
+		data: data,
+		info: ObjectInfo{
+			Key:         key,
+			Size:        int64(len(data)),
+			ContentType: opts.ContentType,
+			ModTime:     time.Now(),
+			ETag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+		},
+	}
+	m.mu.Lock()
+	m.objects[key] = obj
+	m.mu.Unlock()
+	info := obj.info
+	return &info, nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrNotExist
+	}
+	info := obj.info
+	return io.NopCloser(bytes.NewReader(obj.data)), &info, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotExist
+	}
+	info := obj.info
+	return &info, nil
+}
+
+func (m *Memory) URL(key string) string {
+	return joinURL(m.BaseURL, key)
+}
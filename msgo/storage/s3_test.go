@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-west-1"
+	testBucket    = "bucket"
+)
+
+// fakeS3 校验 Signature V4 签名的 S3 服务，对象保存在内存中
+type fakeS3 struct {
+	t         *testing.T
+	pathStyle bool
+	//签名错误是预期的，不作为测试失败
+	allowInvalid bool
+	mu           sync.Mutex
+	objects      map[string]fakeS3Object
+	requests     []string
+}
+
+type fakeS3Object struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f.verify(r); err != nil {
+		if !f.allowInvalid {
+			f.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		}
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+	key, ok := f.key(r)
+	if !ok {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+key)
+	obj, exists := f.objects[key]
+	switch r.Method {
+	case http.MethodPut:
+		//S3 不支持 chunked 上传，必须有 Content-Length
+		if len(r.TransferEncoding) > 0 || r.ContentLength < 0 {
+			http.Error(w, "<Error><Code>MissingContentLength</Code></Error>", http.StatusLengthRequired)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			http.Error(w, "<Error><Code>IncompleteBody</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		obj = fakeS3Object{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now().UTC()}
+		f.objects[key] = obj
+		w.Header().Set("ETag", obj.etag())
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		header := w.Header()
+		header.Set("Content-Type", obj.contentType)
+		header.Set("Content-Length", fmt.Sprint(len(obj.data)))
+		header.Set("ETag", obj.etag())
+		header.Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (o fakeS3Object) etag() string {
+	sum := md5.Sum(o.data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// key 按照访问方式从 Host 或者路径中取出 bucket 和 key
+func (f *fakeS3) key(r *http.Request) (string, bool) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if f.pathStyle {
+		bucket, key, _ := strings.Cut(path, "/")
+		return key, bucket == testBucket
+	}
+	return path, strings.HasPrefix(r.Host, testBucket+".")
+}
+
+// verify 按照 AWS 文档独立计算签名并和 Authorization 比较
+func (f *fakeS3) verify(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return fmt.Errorf("unexpected Authorization %q", auth)
+	}
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || time.Since(signedAt).Abs() > 15*time.Minute {
+		return fmt.Errorf("invalid X-Amz-Date %q", amzDate)
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if fields["Credential"] != testAccessKey+"/"+scope {
+		return fmt.Errorf("invalid Credential %q", fields["Credential"])
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != "UNSIGNED-PAYLOAD" {
+		return fmt.Errorf("invalid X-Amz-Content-Sha256 %q", payloadHash)
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signedHeaders) {
+		return fmt.Errorf("SignedHeaders not sorted: %v", signedHeaders)
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if i := sort.SearchStrings(signedHeaders, name); i == len(signedHeaders) || signedHeaders[i] != name {
+			return fmt.Errorf("%s is not signed", name)
+		}
+	}
+	if r.Header.Get("Content-Type") != "" && !strings.Contains(fields["SignedHeaders"], "content-type") {
+		return fmt.Errorf("content-type is not signed")
+	}
+	for _, name := range signedHeaders {
+		value := r.Host
+		if name != "host" {
+			value = strings.TrimSpace(strings.Join(r.Header.Values(name), ","))
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+
+	//S3 的规范路径只编码一次，请求中的路径必须和规范路径一致
+	canonicalURI := awsURIEncode(r.URL.Path)
+	if requestPath, _, _ := strings.Cut(r.RequestURI, "?"); requestPath != canonicalURI {
+		return fmt.Errorf("request path %q, want %q", requestPath, canonicalURI)
+	}
+	canonicalRequest := r.Method + "\n" + canonicalURI + "\n" + r.URL.RawQuery + "\n" +
+		canonicalHeaders.String() + "\n" + fields["SignedHeaders"] + "\n" + payloadHash
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, data := range []string{amzDate[:8], testRegion, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		key = mac.Sum(nil)
+	}
+	if !hmac.Equal([]byte(hex.EncodeToString(key)), []byte(fields["Signature"])) {
+		return fmt.Errorf("signature mismatch, canonical request:\n%s", canonicalRequest)
+	}
+	return nil
+}
+
+// awsURIEncode 除了 A-Z a-z 0-9 - _ . ~ 和 / 以外都编码为 %XX
+func awsURIEncode(s string) string {
+	const unreserved = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_.~/"
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if strings.IndexByte(unreserved, c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func newFakeS3(t *testing.T, pathStyle bool, config S3Config) (*S3, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, pathStyle: pathStyle, objects: make(map[string]fakeS3Object)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	//虚拟主机方式访问时 Host 为 bucket.127.0.0.1:port，连接统一发往测试服务器
+	addr := server.Listener.Addr().String()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+	config.Endpoint = server.URL
+	config.Bucket = testBucket
+	config.PathStyle = pathStyle
+	config.Client = &http.Client{Transport: transport}
+	if config.AccessKey == "" {
+		config.AccessKey = testAccessKey
+		config.SecretKey = testSecretKey
+	}
+	if config.Region == "" {
+		config.Region = testRegion
+	}
+	s, err := NewS3(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestS3(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		t.Run(fmt.Sprintf("PathStyle=%v", pathStyle), func(t *testing.T) {
+			s, fake := newFakeS3(t, pathStyle, S3Config{})
+			testStorage(t, s)
+			//Put 和 Get 的参数不合法时不发送请求
+			for _, req := range fake.requests {
+				if strings.Contains(req, "..") {
+					t.Fatalf("invalid key sent to server: %s", req)
+				}
+			}
+		})
+	}
+}
+
+func TestS3InvalidSignature(t *testing.T) {
+	s, fake := newFakeS3(t, true, S3Config{AccessKey: testAccessKey, SecretKey: "wrong"})
+	fake.allowInvalid = true
+	_, err := s.Stat(context.Background(), "a.txt")
+	if err == nil || err == ErrNotExist || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Stat with wrong secret: %v", err)
+	}
+	_, err = s.Put(context.Background(), "a.txt", strings.NewReader("a"), PutOptions{Size: 1})
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put with wrong secret: %v", err)
+	}
+}
+
+func TestS3URL(t *testing.T) {
+	tests := []struct {
+		config S3Config
+		want   string
+	}{
+		{S3Config{Endpoint: "https://s3.example.com", Bucket: "b", PathStyle: true}, "https://s3.example.com/b/a%20b/c%2Bd.png"},
+		{S3Config{Endpoint: "https://s3.example.com/", Bucket: "b"}, "https://b.s3.example.com/a%20b/c%2Bd.png"},
+		{S3Config{Endpoint: "https://s3.example.com", Bucket: "b", PublicURL: "https://cdn.example.com/"}, "https://cdn.example.com/a%20b/c+d.png"},
+	}
+	for _, tt := range tests {
+		s, err := NewS3(tt.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.URL("a b/c+d.png"); got != tt.want {
+			t.Errorf("URL = %q, want %q", got, tt.want)
+		}
+	}
+	if _, err := NewS3(S3Config{Endpoint: "s3.example.com"}); err == nil {
+		t.Fatal("endpoint without scheme should fail")
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotExist 对象不存在
+var ErrNotExist = errors.New("storage: object does not exist")
+
+// ErrInvalidKey key 为空、是绝对路径或者包含 .. 等不安全的部分
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage 上传文件的存储后端，key 使用 / 分隔，例如 avatar/2023/a.png
+type Storage interface {
+	// Put 保存对象，已经存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error)
+	// Get 读取对象，调用方负责关闭返回的 io.ReadCloser，不存在时返回 ErrNotExist
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Delete 删除对象，不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// Stat 获取对象的信息，不存在时返回 ErrNotExist
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// URL 返回对象的访问地址
+	URL(key string) string
+}
+
+// PutOptions 保存对象时的附加信息
+type PutOptions struct {
+	// Size 对象的字节数，小于 0 表示未知
+	Size int64
+	// ContentType 对象的 MIME 类型
+	ContentType string
+}
+
+// ObjectInfo 对象的信息
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+}
+
+// CheckKey 检查 key 是否安全，不允许空的部分、. 和 ..，防止访问存储目录以外的文件
+func CheckKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || strings.ContainsRune(key, 0) {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// joinURL 拼接 baseURL 和 key，key 的每一部分都会进行转义
+func joinURL(baseURL, key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckKey(t *testing.T) {
+	valid := []string{"a.png", "avatar/2023/a.png", "a b/c#d.txt", "..a/b..", ".hidden"}
+	for _, key := range valid {
+		if err := CheckKey(key); err != nil {
+			t.Errorf("CheckKey(%q) = %v", key, err)
+		}
+	}
+	invalid := []string{"", "/a", "a/", "a//b", "./a", "a/./b", "..", "../a", "a/../b", "a/..", `a\b`, `..\a`, "a\x00b"}
+	for _, key := range invalid {
+		if err := CheckKey(key); err != ErrInvalidKey {
+			t.Errorf("CheckKey(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+// testStorage 所有实现都需要满足的行为
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	key := "dir/sub dir/a+b.txt"
+
+	if _, err := s.Stat(ctx, key); err != ErrNotExist {
+		t.Fatalf("Stat missing object: %v", err)
+	}
+	if _, _, err := s.Get(ctx, key); err != ErrNotExist {
+		t.Fatalf("Get missing object: %v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete missing object: %v", err)
+	}
+	if _, err := s.Put(ctx, "../a.txt", strings.NewReader("x"), PutOptions{Size: 1}); err != ErrInvalidKey {
+		t.Fatalf("Put invalid key: %v", err)
+	}
+
+	info, err := s.Put(ctx, key, strings.NewReader("hello"), PutOptions{Size: 5, ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != key || info.Size != 5 || info.ETag == "" {
+		t.Fatalf("Put info = %+v", info)
+	}
+	//大小未知时也能保存
+	if _, err := s.Put(ctx, key, io.MultiReader(strings.NewReader("hello "), strings.NewReader("world")), PutOptions{Size: -1, ContentType: "text/plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(ctx, "empty.txt", strings.NewReader(""), PutOptions{Size: 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, info, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello world" || info.Size != 11 || !strings.HasPrefix(info.ContentType, "text/plain") {
+		t.Fatalf("Get = %q, %+v", data, info)
+	}
+	if info, err = s.Stat(ctx, key); err != nil || info.Size != 11 || info.Key != key {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stat(ctx, key); err != ErrNotExist {
+		t.Fatalf("Stat after Delete: %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory("https://cdn.example.com/")
+	testStorage(t, m)
+	if got := m.URL("a b/c#d.png"); got != "https://cdn.example.com/a%20b/c%23d.png" {
+		t.Fatalf("URL = %q", got)
+	}
+
+	//返回的信息是副本，修改不影响保存的对象
+	ctx := context.Background()
+	info, _ := m.Put(ctx, "a.txt", strings.NewReader("a"), PutOptions{Size: 1})
+	info.Size = 100
+	if stat, _ := m.Stat(ctx, "a.txt"); stat.Size != 1 {
+		t.Fatalf("stored size changed to %d", stat.Size)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := m.Put(cancelled, "b.txt", strings.NewReader("b"), PutOptions{Size: 1}); err != context.Canceled {
+		t.Fatalf("Put with cancelled context: %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "upload")
+	l, err := NewLocal(root, "/static/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, l)
+	if got := l.URL("a b/c#d.png"); got != "/static/upload/a%20b/c%23d.png" {
+		t.Fatalf("URL = %q", got)
+	}
+
+	ctx := context.Background()
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Stat(ctx, "dir"); err != ErrNotExist {
+		t.Fatalf("Stat directory: %v", err)
+	}
+	if _, _, err := l.Get(ctx, "dir"); err != ErrNotExist {
+		t.Fatalf("Get directory: %v", err)
+	}
+}
+
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestLocalPutAtomic(t *testing.T) {
+	root := t.TempDir()
+	l, _ := NewLocal(root, "")
+	ctx := context.Background()
+	if _, err := l.Put(ctx, "a/b.txt", strings.NewReader("old"), PutOptions{Size: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	//写入失败时保留原来的文件，并删除临时文件
+	errRead := errors.New("read failed")
+	if _, err := l.Put(ctx, "a/b.txt", &failingReader{data: "new content", err: errRead}, PutOptions{Size: -1}); err != errRead {
+		t.Fatalf("Put with failing reader: %v", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.Put(cancelled, "a/b.txt", strings.NewReader("new"), PutOptions{Size: 3}); err != context.Canceled {
+		t.Fatalf("Put with cancelled context: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "a", "b.txt"))
+	if string(data) != "old" {
+		t.Fatalf("content = %q, want old", data)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, "a"))
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left: %v", entries)
+	}
+}
+
+func TestLocalPutFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file mode is not supported on windows")
+	}
+	root := t.TempDir()
+	l, _ := NewLocal(root, "")
+	if _, err := l.Put(context.Background(), "a.txt", strings.NewReader("a"), PutOptions{Size: 1}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(root, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//不受 umask 影响，和 CreateTemp 的 0600 不同
+	if mode := info.Mode().Perm(); mode != localFileMode {
+		t.Fatalf("mode = %v, want %v", mode, os.FileMode(localFileMode))
+	}
+}
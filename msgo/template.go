@@ -0,0 +1,207 @@
+package msgo
+
+import (
+	"fmt"
+	"github.com/mis403/msgo/render"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// TemplateLayout 布局模板的配置，Layout 和 Partials 都是相对 Root 的路径，使用 / 分隔。
+// Root 下除布局和公共片段以外的文件都是页面，页面名为相对 Root 的路径，例如 admin/index.html，
+// 不同目录下的同名页面互不冲突。布局中使用 {{block "content" .}}{{end}} 预留位置，页面中使用
+// {{define "content"}}...{{end}} 填充；公共片段可以通过文件路径或者其中 define 的名字引用
+type TemplateLayout struct {
+	// FS 模板所在的文件系统，例如 embed.FS，为空时使用本地文件系统
+	FS fs.FS
+	// Root 模板的根目录，为空时使用当前目录
+	Root string
+	// Layout 布局文件，例如 layouts/base.html，为空时页面单独渲染
+	Layout string
+	// Partials 公共片段的 glob 模式，例如 partials/*.html
+	Partials []string
+	// Extension 页面文件的扩展名，默认为 .html
+	Extension string
+}
+
+// LoadTemplateLayout 按照布局加载模板，每个页面单独和布局、公共片段一起解析
+func (e *Engine) LoadTemplateLayout(layout TemplateLayout) {
+	root := layout.Root
+	if root == "" {
+		root = "."
+	}
+	fsys := layout.FS
+	if fsys == nil {
+		fsys = os.DirFS(root)
+	} else if root != "." {
+		sub, err := fs.Sub(fsys, root)
+		if err != nil {
+			panic(err)
+		}
+		fsys = sub
+	}
+	e.loadTemplate(func() (render.HTMLRender, error) {
+		templates, err := parseLayoutTemplates(fsys, layout, e.templateFuncs())
+		if err != nil {
+			return nil, err
+		}
+		return render.HTMLLayout{Templates: templates, Layout: layout.Layout}, nil
+	}, func() (map[string]time.Time, error) {
+		files := make([]string, 0)
+		err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files = append(files, name)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return fsModTimes(fsys, files)
+	})
+}
+
+// LoadTemplateFS 从 fs.FS 中加载模板，例如使用 //go:embed 嵌入到程序中的模板，模板名为文件名
+func (e *Engine) LoadTemplateFS(fsys fs.FS, patterns ...string) {
+	e.loadTemplate(func() (render.HTMLRender, error) {
+		t, err := template.New("").Funcs(e.templateFuncs()).ParseFS(fsys, patterns...)
+		if err != nil {
+			return nil, err
+		}
+		return render.HTMLProduction{Template: t}, nil
+	}, func() (map[string]time.Time, error) {
+		files := make([]string, 0)
+		for _, pattern := range patterns {
+			matches, err := fs.Glob(fsys, pattern)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+		return fsModTimes(fsys, files)
+	})
+}
+
+// LoadTemplateFiles 加载指定的模板文件，模板名为文件名
+func (e *Engine) LoadTemplateFiles(files ...string) {
+	e.loadTemplate(func() (render.HTMLRender, error) {
+		t, err := template.New("").Funcs(e.templateFuncs()).ParseFiles(files...)
+		if err != nil {
+			return nil, err
+		}
+		return render.HTMLProduction{Template: t}, nil
+	}, func() (map[string]time.Time, error) {
+		return fileModTimes(files)
+	})
+}
+
+// templatePollInterval 开发模式下检查模板文件是否修改的最小间隔，测试中会调小
+var templatePollInterval = time.Second
+
+// loadTemplate 加载模板，解析失败时 panic。开发模式下使用 render.HTMLDebug，模板文件变化时重新调用 load
+func (e *Engine) loadTemplate(load func() (render.HTMLRender, error), watch func() (map[string]time.Time, error)) {
+	if IsDebugging() {
+		htmlRender, err := render.NewHTMLDebug(load, watch, templatePollInterval)
+		if err != nil {
+			panic(err)
+		}
+		e.HTMLRender = htmlRender
+		return
+	}
+	htmlRender, err := load()
+	if err != nil {
+		panic(err)
+	}
+	e.HTMLRender = htmlRender
+}
+
+func fileModTimes(files []string) (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time, len(files))
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[name] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// fsModTimes embed.FS 中文件的修改时间都是零值，不会触发重新加载
+func fsModTimes(fsys fs.FS, files []string) (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time, len(files))
+	for _, name := range files {
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[name] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func parseLayoutTemplates(fsys fs.FS, layout TemplateLayout, funcMap template.FuncMap) (map[string]*template.Template, error) {
+	ext := layout.Extension
+	if ext == "" {
+		ext = ".html"
+	}
+	base := template.New("").Funcs(funcMap)
+	shared := make(map[string]bool)
+	files := make([]string, 0)
+	if layout.Layout != "" {
+		files = append(files, layout.Layout)
+	}
+	for _, pattern := range layout.Partials {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	for _, name := range files {
+		if err := parseTemplateFile(base, fsys, name); err != nil {
+			return nil, err
+		}
+		shared[name] = true
+	}
+
+	templates := make(map[string]*template.Template)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || shared[name] || !strings.HasSuffix(name, ext) {
+			return nil
+		}
+		t, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if err := parseTemplateFile(t, fsys, name); err != nil {
+			return err
+		}
+		templates[name] = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("msgo: no templates found with extension %s", ext)
+	}
+	return templates, nil
+}
+
+// parseTemplateFile 以文件的完整路径作为模板名解析，避免不同目录下的同名文件互相覆盖
+func parseTemplateFile(t *template.Template, fsys fs.FS, name string) error {
+	content, err := fs.ReadFile(fsys, path.Clean(name))
+	if err != nil {
+		return err
+	}
+	_, err = t.New(name).Parse(string(content))
+	return err
+}
@@ -0,0 +1,129 @@
+package msgo
+
+import (
+	"fmt"
+	"github.com/mis403/msgo/codec"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CSRFFieldName csrfField 生成的隐藏字段名
+const CSRFFieldName = "_csrf"
+
+// DefaultFuncMap 加载模板时默认注册的函数，SetFuncMap 中的同名函数会覆盖默认函数
+func DefaultFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"formatDate": formatDate,
+		"safeHTML":   func(s string) template.HTML { return template.HTML(s) },
+		"safeJS":     func(s string) template.JS { return template.JS(s) },
+		"safeURL":    func(s string) template.URL { return template.URL(s) },
+		"safeCSS":    func(s string) template.CSS { return template.CSS(s) },
+		"json":       toJSON,
+		"urlFor":     urlFor,
+		"csrfField":  csrfField,
+		"dict":       dict,
+	}
+}
+
+// templateFuncs 默认函数和 SetFuncMap 设置的函数合并之后的结果
+func (e *Engine) templateFuncs() template.FuncMap {
+	funcMap := DefaultFuncMap()
+	for name, fn := range e.funcMap {
+		funcMap[name] = fn
+	}
+	return funcMap
+}
+
+// formatDate 格式化时间，layout 默认为 2006-01-02 15:04:05
+func formatDate(t time.Time, layout ...string) string {
+	if len(layout) > 0 {
+		return t.Format(layout[0])
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// toJSON 输出 JSON，用于在 <script> 中给变量赋值
+func toJSON(v any) (template.JS, error) {
+	data, err := codec.JSON.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// urlFor 使用参数替换路由中的 :name、* 和 **，参数名和路由解析时的路径参数一致，
+// * 对应一段路径，** 对应剩余的多段路径，每一段都会进行转义，多余的参数作为 query 参数，例如
+// {{urlFor "/user/:id" "id" 1 "page" 2}} 输出 /user/1?page=2，
+// {{urlFor "/static/**" "**" "css/a b.css"}} 输出 /static/css/a%20b.css
+func urlFor(path string, pairs ...any) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("urlFor: odd number of arguments")
+	}
+	params := make(map[string]string, len(pairs)/2)
+	keys := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key := fmt.Sprint(pairs[i])
+		params[key] = fmt.Sprint(pairs[i+1])
+		keys = append(keys, key)
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		var name string
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name = segment[1:]
+		case segment == "*", segment == "**":
+			name = segment
+		default:
+			continue
+		}
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("urlFor: missing parameter %s for %s", name, path)
+		}
+		delete(params, name)
+		if name == "**" {
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	result := strings.Join(segments, "/")
+	query := url.Values{}
+	for _, key := range keys {
+		if value, ok := params[key]; ok {
+			query.Set(key, value)
+		}
+	}
+	if len(query) > 0 {
+		result += "?" + query.Encode()
+	}
+	return result, nil
+}
+
+// csrfField 生成携带 CSRF token 的隐藏表单字段
+func csrfField(token string) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + CSRFFieldName + `" value="` + template.HTMLEscapeString(token) + `">`)
+}
+
+// dict 将成对的 key、value 组装成 map，用于向公共片段传递多个值，例如 {{template "card" dict "title" .Title "user" .User}}
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: odd number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
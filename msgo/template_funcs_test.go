@@ -0,0 +1,96 @@
+package msgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestURLFor(t *testing.T) {
+	tests := []struct {
+		path  string
+		pairs []any
+		want  string
+	}{
+		{"/user/:id", []any{"id", 1, "page", 2}, "/user/1?page=2"},
+		{"/user/:name", []any{"name", "a/b c"}, "/user/a%2Fb%20c"},
+		{"/file/*/info", []any{"*", "a b"}, "/file/a%20b/info"},
+		{"/static/**", []any{"**", "css/a b.css"}, "/static/css/a%20b.css"},
+		{"/search", []any{"q", "a&b", "tag", "x"}, "/search?q=a%26b&tag=x"},
+	}
+	for _, tt := range tests {
+		got, err := urlFor(tt.path, tt.pairs...)
+		if err != nil || got != tt.want {
+			t.Errorf("urlFor(%q, %v) = %q, %v, want %q", tt.path, tt.pairs, got, err, tt.want)
+		}
+	}
+	if _, err := urlFor("/static/**", "*", "a"); err == nil {
+		t.Fatal("** should not be filled by the * parameter")
+	}
+	if _, err := urlFor("/user/:id", "id"); err == nil {
+		t.Fatal("odd number of arguments should fail")
+	}
+}
+
+// TestURLForMatchesRoute urlFor 生成的地址经过路由解析之后能得到相同的参数
+func TestURLForMatchesRoute(t *testing.T) {
+	engine := NewEngine()
+	var got string
+	engine.Group("static").Get("/**", func(ctx *Context) {
+		got = ctx.Param("**")
+	})
+	u, err := urlFor("/static/**", "**", "css/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	if got != "css/site.css" {
+		t.Fatalf("param ** = %q", got)
+	}
+}
+
+type templatePage struct {
+	Title string
+}
+
+func TestTemplateData(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(file, []byte(`{{.user}}|{{.title}}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine()
+	engine.LoadTemplateFiles(file)
+
+	type namedMap map[string]string
+	tests := []struct {
+		name string
+		data any
+		code int
+		body string
+	}{
+		{"nil", nil, http.StatusOK, "msgo|"},
+		{"map", map[string]any{"title": "home"}, http.StatusOK, "msgo|home"},
+		{"override", map[string]any{"user": "other"}, http.StatusOK, "other|"},
+		{"named map", namedMap{"title": "home"}, http.StatusOK, "msgo|home"},
+		{"struct", templatePage{Title: "home"}, http.StatusInternalServerError, ""},
+		{"string", "home", http.StatusInternalServerError, ""},
+	}
+	g := engine.Group("t")
+	for _, tt := range tests {
+		data := tt.data
+		g.HandleE(http.MethodGet, "/"+strings.ReplaceAll(tt.name, " ", "-"), func(ctx *Context) error {
+			ctx.SetTemplateData("user", "msgo")
+			return ctx.Template("page.html", data)
+		})
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/"+strings.ReplaceAll(tt.name, " ", "-"), nil))
+		if w.Code != tt.code || (tt.code == http.StatusOK && w.Body.String() != tt.body) {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
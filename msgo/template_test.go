@@ -0,0 +1,220 @@
+package msgo
+
+import (
+	"fmt"
+	"github.com/mis403/msgo/render"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	texttemplate "text/template"
+)
+
+// templateRenderer 注册渲染模板的路由，返回的函数按照模板名渲染，返回状态码和响应内容
+func templateRenderer(engine *Engine, data any) func(name string) (int, string) {
+	engine.Group("t").HandleE(http.MethodGet, "/**", func(ctx *Context) error {
+		return ctx.Template(ctx.Param("**"), data)
+	})
+	return func(name string) (int, string) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/"+name, nil))
+		return w.Code, w.Body.String()
+	}
+}
+
+var layoutFiles = fstest.MapFS{
+	"views/layouts/base.html":  {Data: []byte(`<html>{{template "header" .}}{{block "content" .}}default{{end}}</html>`)},
+	"views/partials/head.html": {Data: []byte(`{{define "header"}}<h1>{{.title}}</h1>{{end}}`)},
+	"views/index.html":         {Data: []byte(`{{define "content"}}home {{.title}}{{end}}`)},
+	"views/admin/index.html":   {Data: []byte(`{{define "content"}}admin{{end}}`)},
+	"views/empty.html":         {Data: []byte(``)},
+	"views/readme.txt":         {Data: []byte(`not a template`)},
+}
+
+func TestLoadTemplateLayout(t *testing.T) {
+	engine := NewEngine()
+	engine.LoadTemplateLayout(TemplateLayout{
+		FS:       layoutFiles,
+		Root:     "views",
+		Layout:   "layouts/base.html",
+		Partials: []string{"partials/*.html"},
+	})
+	get := templateRenderer(engine, map[string]string{"title": "msgo"})
+	tests := []struct {
+		name string
+		code int
+		body string
+	}{
+		{"index.html", http.StatusOK, "<html><h1>msgo</h1>home msgo</html>"},
+		//不同目录下的同名页面互不冲突
+		{"admin/index.html", http.StatusOK, "<html><h1>msgo</h1>admin</html>"},
+		{"empty.html", http.StatusOK, "<html><h1>msgo</h1>default</html>"},
+		{"readme.txt", http.StatusInternalServerError, ""},
+		{"layouts/base.html", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		code, body := get(tt.name)
+		if code != tt.code || (tt.code == http.StatusOK && body != tt.body) {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, code, body, tt.code, tt.body)
+		}
+	}
+
+	//没有布局时页面单独渲染
+	engine = NewEngine()
+	engine.LoadTemplateLayout(TemplateLayout{FS: layoutFiles, Root: "views/admin"})
+	if code, body := templateRenderer(engine, nil)("index.html"); code != http.StatusOK || body != "" {
+		t.Errorf("without layout: %d %q", code, body)
+	}
+}
+
+func TestLoadTemplateLayoutDefaultRoot(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range layoutFiles {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, file.Data, 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "views")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	//Root 为空时使用当前目录
+	engine := NewEngine()
+	engine.LoadTemplateLayout(TemplateLayout{Layout: "layouts/base.html", Partials: []string{"partials/*.html"}})
+	if code, body := templateRenderer(engine, map[string]string{"title": "a"})("admin/index.html"); code != http.StatusOK || body != "<html><h1>a</h1>admin</html>" {
+		t.Fatalf("%d %q", code, body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("missing layout should panic")
+		}
+	}()
+	NewEngine().LoadTemplateLayout(TemplateLayout{Layout: "layouts/missing.html"})
+}
+
+func TestLoadTemplateFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/index.html":  {Data: []byte(`{{template "header.html" .}}index {{upper .}}`)},
+		"templates/header.html": {Data: []byte(`<h1>{{.}}</h1>`)},
+		"templates/page.tmpl":   {Data: []byte(`tmpl`)},
+	}
+	engine := NewEngine()
+	engine.SetFuncMap(map[string]any{"upper": strings.ToUpper})
+	engine.LoadTemplateFS(fsys, "templates/*.html")
+	get := templateRenderer(engine, "<a>")
+	tests := []struct {
+		name string
+		code int
+		body string
+	}{
+		//模板名为文件名，内容经过 HTML 转义
+		{"index.html", http.StatusOK, "<h1>&lt;a&gt;</h1>index &lt;A&gt;"},
+		{"header.html", http.StatusOK, "<h1>&lt;a&gt;</h1>"},
+		{"page.tmpl", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		code, body := get(tt.name)
+		if code != tt.code || (tt.code == http.StatusOK && body != tt.body) {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, code, body, tt.code, tt.body)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("pattern without matches should panic")
+		}
+	}()
+	NewEngine().LoadTemplateFS(fsys, "missing/*.html")
+}
+
+func TestLoadTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.html": `a {{.}}`, "b.html": `b {{template "a.html" .}}`}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := NewEngine()
+	engine.LoadTemplateFiles(filepath.Join(dir, "a.html"), filepath.Join(dir, "b.html"))
+	get := templateRenderer(engine, "x")
+	if code, body := get("b.html"); code != http.StatusOK || body != "b a x" {
+		t.Fatalf("b.html: %d %q", code, body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("missing file should panic")
+		}
+	}()
+	NewEngine().LoadTemplateFiles(filepath.Join(dir, "missing.html"))
+}
+
+// upperRender 自定义的模板引擎，把模板名和数据转换成大写输出
+type upperRender struct{}
+
+func (upperRender) Instance(name string, data any) render.Render {
+	return &render.String{Format: "%s %s", Data: []any{strings.ToUpper(name), strings.ToUpper(fmt.Sprint(data))}}
+}
+
+func TestCustomHTMLRender(t *testing.T) {
+	engine := NewEngine()
+	engine.HTMLRender = upperRender{}
+	if code, body := templateRenderer(engine, "msgo")("index"); code != http.StatusOK || body != "INDEX MSGO" {
+		t.Fatalf("%d %q", code, body)
+	}
+
+	//Negotiate 使用同一个模板引擎
+	engine = NewEngine()
+	engine.HTMLRender = upperRender{}
+	engine.Group("t").HandleE(http.MethodGet, "/page", func(ctx *Context) error {
+		return ctx.Negotiate(http.StatusOK, Offers{HTMLName: "page", HTML: "a"})
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/page", nil))
+	if w.Body.String() != "PAGE A" {
+		t.Fatalf("negotiate: %q", w.Body.String())
+	}
+}
+
+func TestTextTemplate(t *testing.T) {
+	tmpl := texttemplate.Must(texttemplate.New("mail.txt").Parse(`Hi <{{.}}>`))
+	tests := []struct {
+		render      render.HTMLRender
+		contentType string
+	}{
+		{render.TextTemplate{Template: tmpl}, "text/plain; charset=utf-8"},
+		{render.TextTemplate{Template: tmpl, ContentType: "text/markdown; charset=utf-8"}, "text/markdown; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		engine := NewEngine()
+		engine.HTMLRender = tt.render
+		engine.Group("t").HandleE(http.MethodGet, "/**", func(ctx *Context) error {
+			return ctx.Template(ctx.Param("**"), "a&b")
+		})
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/mail.txt", nil))
+		//text/template 不做 HTML 转义
+		if w.Code != http.StatusOK || w.Body.String() != "Hi <a&b>" || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/missing.txt", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("missing template: %d", w.Code)
+		}
+	}
+}
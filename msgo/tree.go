@@ -81,3 +81,25 @@ func (t *treeNode) Get(path string) *treeNode {
 	}
 	return nil
 }
+
+// parseParams 对比路由规则和请求路径，取出 :name 、* 和 ** 对应的路径参数
+func parseParams(pattern, path string) map[string]string {
+	params := make(map[string]string)
+	patterns := strings.Split(pattern, "/")
+	paths := strings.Split(path, "/")
+	for index, name := range patterns {
+		if index >= len(paths) {
+			break
+		}
+		switch {
+		case strings.HasPrefix(name, ":"):
+			params[name[1:]] = paths[index]
+		case name == "*":
+			params[name] = paths[index]
+		case name == "**":
+			params[name] = strings.Join(paths[index:], "/")
+			return params
+		}
+	}
+	return params
+}
@@ -0,0 +1,392 @@
+package msgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/mis403/msgo/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sniffLen MIME 嗅探时读取的文件头部字节数
+const sniffLen = 3072
+
+// maxFilenameLen 文件名的最大字节数
+const maxFilenameLen = 255
+
+// SaveUploadedFiles 中普通表单字段的默认限制，和 multipart.Reader.ReadForm 类似
+const (
+	defaultMaxFormFields = 1000
+	defaultMaxFormSize   = 10 << 20
+)
+
+// UploadConfig 上传文件的限制
+type UploadConfig struct {
+	// Dir 保存文件的目录，不存在时自动创建
+	Dir string
+	// MaxFileSize 单个文件的最大字节数，0 表示不限制
+	MaxFileSize int64
+	// MaxTotalSize 所有文件的总字节数，0 表示不限制
+	MaxTotalSize int64
+	// MaxFiles 最多上传的文件数，0 表示不限制
+	MaxFiles int
+	// AllowedTypes 允许的 MIME 类型，根据文件内容检测，支持 image/* 的形式，为空时不限制。
+	// 只匹配检测出的类型本身，例如 text/plain 不允许 text/html；image/svg+xml 可以包含脚本，需要单独列出
+	AllowedTypes []string
+	// AllowParentTypes 检测出的类型的父类型在 AllowedTypes 中时也允许，例如 text/plain 允许所有文本文件
+	AllowParentTypes bool
+	// Fields 只接收这些表单字段中的文件，为空时接收所有字段
+	Fields []string
+	// MaxFormFields 普通表单字段（非文件）的最大数量，默认为 1000，小于 0 表示不限制
+	MaxFormFields int
+	// MaxFormSize 所有普通表单字段的值的总字节数，默认为 10MB，小于 0 表示不限制
+	MaxFormSize int64
+}
+
+// UploadedFile 已经保存到磁盘的上传文件
+type UploadedFile struct {
+	// Field 表单字段名
+	Field string
+	// Filename 清理之后的文件名
+	Filename string
+	// OriginalFilename 客户端提交的文件名，不能直接用于拼接路径
+	OriginalFilename string
+	// Path 文件的保存路径
+	Path string
+	// Size 文件的字节数
+	Size int64
+	// ContentType 根据文件内容检测出的 MIME 类型
+	ContentType string
+}
+
+// FormFile 返回表单中 name 字段的第一个文件，字段不存在时返回 http.ErrMissingFile
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	files, err := c.FormFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// FormFiles 返回表单中 name 字段的所有文件
+func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files, nil
+}
+
+// SaveUploadedFile 将上传的文件保存到 dst，目录不存在时自动创建。
+// dst 不要直接使用 file.Filename 拼接，需要先经过 SanitizeFilename 处理
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// SaveUploadedFileTo 将上传的文件保存到 store，key 由调用方生成，不要直接使用 file.Filename。
+// 保存的 MIME 类型根据文件内容检测
+func (c *Context) SaveUploadedFileTo(file *multipart.FileHeader, store storage.Storage, key string) (*storage.ObjectInfo, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	mtype, err := mimetype.DetectReader(src)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return store.Put(c.R.Context(), key, src, storage.PutOptions{
+		Size:        file.Size,
+		ContentType: mtype.String(),
+	})
+}
+
+// ValidateUploadedFile 按照 config 中的 MaxFileSize 和 AllowedTypes 检查已经解析的上传文件，返回检测出的 MIME 类型
+func ValidateUploadedFile(file *multipart.FileHeader, config UploadConfig) (string, error) {
+	if config.MaxFileSize > 0 && file.Size > config.MaxFileSize {
+		return "", fileTooLargeError(file.Filename, config.MaxFileSize)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	mtype, err := mimetype.DetectReader(src)
+	if err != nil {
+		return "", err
+	}
+	if !allowedType(mtype, config) {
+		return "", unsupportedTypeError(file.Filename, mtype.String())
+	}
+	return mtype.String(), nil
+}
+
+// SaveUploadedFiles 逐个读取 multipart 请求中的文件并直接写入 config.Dir，不会把整个文件读入内存。
+// 文件名经过 SanitizeFilename 处理，重名时自动加上序号。超过限制时返回状态码为 413 的 HTTPError，
+// 类型不允许时返回 415，出错时已经保存的文件会被删除。
+// 请求中的普通表单字段可以在之后通过 GetPostForm 等方法获取，字段的数量和总大小受 MaxFormFields、MaxFormSize 限制
+func (c *Context) SaveUploadedFiles(config UploadConfig) ([]*UploadedFile, error) {
+	reader, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	if err := os.MkdirAll(config.Dir, 0750); err != nil {
+		return nil, err
+	}
+	if config.MaxFormFields == 0 {
+		config.MaxFormFields = defaultMaxFormFields
+	}
+	if config.MaxFormSize == 0 {
+		config.MaxFormSize = defaultMaxFormSize
+	}
+	saved := make([]*UploadedFile, 0)
+	values := make(url.Values)
+	var total int64
+	fields := 0
+	//普通字段保存在内存中，数量和总大小都需要限制；不限制总大小时单个字段仍然不能超过 defaultMaxMemory
+	formSize := config.MaxFormSize
+	fail := func(err error) ([]*UploadedFile, error) {
+		for _, f := range saved {
+			_ = os.Remove(f.Path)
+		}
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if part.FileName() == "" {
+			//普通的表单字段
+			fields++
+			if config.MaxFormFields > 0 && fields > config.MaxFormFields {
+				part.Close()
+				return fail(NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many form fields, at most %d", config.MaxFormFields)))
+			}
+			limit := int64(defaultMaxMemory)
+			if formSize >= 0 {
+				limit = formSize
+			}
+			value, err := io.ReadAll(io.LimitReader(part, limit+1))
+			part.Close()
+			if err != nil {
+				return fail(err)
+			}
+			if int64(len(value)) > limit {
+				return fail(NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("form fields exceed %d bytes", limit)))
+			}
+			if formSize >= 0 {
+				formSize -= int64(len(value))
+			}
+			values.Add(part.FormName(), string(value))
+			continue
+		}
+		if len(config.Fields) > 0 && !containsString(config.Fields, part.FormName()) {
+			part.Close()
+			continue
+		}
+		if config.MaxFiles > 0 && len(saved) >= config.MaxFiles {
+			part.Close()
+			return fail(NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many files, at most %d", config.MaxFiles)))
+		}
+		f, err := saveUploadPart(part, config, total)
+		part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		total += f.Size
+		saved = append(saved, f)
+	}
+	c.formCache = values
+	return saved, nil
+}
+
+// saveUploadPart 先读取文件头部检测 MIME 类型，通过之后再边读边写入磁盘
+func saveUploadPart(part *multipart.Part, config UploadConfig, total int64) (*UploadedFile, error) {
+	original := part.FileName()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	mtype := mimetype.Detect(head)
+	if !allowedType(mtype, config) {
+		return nil, unsupportedTypeError(original, mtype.String())
+	}
+
+	//同时受单个文件和总大小的限制
+	limit := int64(-1)
+	if config.MaxFileSize > 0 {
+		limit = config.MaxFileSize
+	}
+	if config.MaxTotalSize > 0 && (limit < 0 || config.MaxTotalSize-total < limit) {
+		limit = config.MaxTotalSize - total
+	}
+
+	out, filename, err := createUniqueFile(config.Dir, SanitizeFilename(original))
+	if err != nil {
+		return nil, err
+	}
+	src := io.MultiReader(bytes.NewReader(head), part)
+	if limit >= 0 {
+		src = io.LimitReader(src, limit+1)
+	}
+	size, err := io.Copy(out, src)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && limit >= 0 && size > limit {
+		if config.MaxFileSize > 0 && size > config.MaxFileSize {
+			err = fileTooLargeError(original, config.MaxFileSize)
+		} else {
+			err = NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("total upload size exceeds %d bytes", config.MaxTotalSize))
+		}
+	}
+	path := filepath.Join(config.Dir, filename)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return &UploadedFile{
+		Field:            part.FormName(),
+		Filename:         filename,
+		OriginalFilename: original,
+		Path:             path,
+		Size:             size,
+		ContentType:      mtype.String(),
+	}, nil
+}
+
+// createUniqueFile 在 dir 中创建文件，文件已存在时在扩展名之前加上序号，例如 a-1.png
+func createUniqueFile(dir, filename string) (*os.File, string, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	name := filename
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+		if err == nil {
+			return f, name, nil
+		}
+		if !errors.Is(err, os.ErrExist) || i > 1000 {
+			return nil, "", err
+		}
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+}
+
+// SanitizeFilename 清理客户端提交的文件名：去掉目录部分、控制字符和文件系统不允许的字符，
+// 去掉开头的 . 防止生成隐藏文件，长度限制为 255 字节，清理之后为空时返回 file
+func SanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	name = strings.TrimRight(name, ". ")
+	if len(name) > maxFilenameLen {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = truncateUTF8(strings.TrimSuffix(name, ext), maxFilenameLen-len(ext)) + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// truncateUTF8 按字节截断字符串，不截断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// scriptableTypes 可以包含脚本的类型，不通过 image/* 这样的通配符匹配
+var scriptableTypes = []string{"image/svg+xml"}
+
+// allowedType 检测出的类型在 AllowedTypes 中，AllowParentTypes 为 true 时父类型在其中也可以
+func allowedType(mtype *mimetype.MIME, config UploadConfig) bool {
+	if len(config.AllowedTypes) == 0 {
+		return true
+	}
+	for m := mtype; m != nil; m = m.Parent() {
+		for _, a := range config.AllowedTypes {
+			if strings.HasSuffix(a, "/*") {
+				if strings.HasPrefix(m.String(), strings.TrimSuffix(a, "*")) && !containsString(scriptableTypes, m.String()) {
+					return true
+				}
+			} else if m.Is(a) {
+				return true
+			}
+		}
+		if !config.AllowParentTypes {
+			break
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fileTooLargeError(filename string, limit int64) *HTTPError {
+	return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file %s exceeds %d bytes", SanitizeFilename(filename), limit))
+}
+
+func unsupportedTypeError(filename, mime string) *HTTPError {
+	return NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("file %s has unsupported type %s", SanitizeFilename(filename), mime))
+}
@@ -0,0 +1,179 @@
+package msgo
+
+import (
+	"bytes"
+	"github.com/gabriel-vasile/mimetype"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type uploadPart struct {
+	field    string
+	filename string
+	content  string
+}
+
+// runSaveUploadedFiles 使用 parts 构造 multipart 请求并调用 SaveUploadedFiles，name 为之后通过 GetPostForm 读取的 name 字段
+func runSaveUploadedFiles(t *testing.T, config UploadConfig, parts ...uploadPart) (files []*UploadedFile, name string, err error) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		if p.filename == "" {
+			_ = mw.WriteField(p.field, p.content)
+			continue
+		}
+		w, err := mw.CreateFormFile(p.field, p.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(p.content))
+	}
+	_ = mw.Close()
+
+	engine := NewEngine()
+	engine.Group("t").Post("/upload", func(c *Context) {
+		files, err = c.SaveUploadedFiles(config)
+		name, _ = c.GetPostForm("name")
+	})
+	r := httptest.NewRequest(http.MethodPost, "/t/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+	return files, name, err
+}
+
+func expectHTTPError(t *testing.T, err error, code int) {
+	t.Helper()
+	he, ok := err.(*HTTPError)
+	if !ok || he.Code != code {
+		t.Fatalf("err = %v, want HTTPError %d", err, code)
+	}
+}
+
+func TestSaveUploadedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files, _, err := runSaveUploadedFiles(t, UploadConfig{Dir: dir, AllowedTypes: []string{"text/plain"}},
+		uploadPart{"name", "", "msgo"},
+		uploadPart{"file", "../../a.txt", "hello"},
+		uploadPart{"file", "a.txt", "world"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Filename != "a.txt" || files[1].Filename != "a-1.txt" {
+		t.Fatalf("files = %+v", files)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "a-1.txt"))
+	if string(data) != "world" || files[0].ContentType != "text/plain; charset=utf-8" {
+		t.Fatalf("saved %q as %s", data, files[0].ContentType)
+	}
+}
+
+func TestSaveUploadedFilesLimits(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16)
+	tests := []struct {
+		name   string
+		config UploadConfig
+		parts  []uploadPart
+		code   int
+	}{
+		{"file too large", UploadConfig{MaxFileSize: 4}, []uploadPart{{"file", "a.txt", "hello"}}, http.StatusRequestEntityTooLarge},
+		{"total too large", UploadConfig{MaxTotalSize: 8}, []uploadPart{{"file", "a.txt", "hello"}, {"file", "b.txt", "hello"}}, http.StatusRequestEntityTooLarge},
+		{"too many files", UploadConfig{MaxFiles: 1}, []uploadPart{{"file", "a.txt", "a"}, {"file", "b.txt", "b"}}, http.StatusRequestEntityTooLarge},
+		{"type not allowed", UploadConfig{AllowedTypes: []string{"image/*"}}, []uploadPart{{"file", "a.png", "hello"}}, http.StatusUnsupportedMediaType},
+		{"too many form fields", UploadConfig{MaxFormFields: 2}, []uploadPart{{"a", "", "1"}, {"b", "", "2"}, {"c", "", "3"}}, http.StatusRequestEntityTooLarge},
+		{"form fields too large", UploadConfig{MaxFormSize: 5}, []uploadPart{{"a", "", "123"}, {"b", "", "456"}}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Dir = t.TempDir()
+			_, _, err := runSaveUploadedFiles(t, tt.config, tt.parts...)
+			expectHTTPError(t, err, tt.code)
+			//出错时已经保存的文件会被删除
+			if entries, _ := os.ReadDir(tt.config.Dir); len(entries) != 0 {
+				t.Fatalf("%d files left", len(entries))
+			}
+		})
+	}
+	t.Run("image allowed", func(t *testing.T) {
+		files, _, err := runSaveUploadedFiles(t, UploadConfig{Dir: t.TempDir(), AllowedTypes: []string{"image/*"}}, uploadPart{"file", "a.png", png})
+		if err != nil || len(files) != 1 || files[0].ContentType != "image/png" {
+			t.Fatalf("files = %+v, err = %v", files, err)
+		}
+	})
+}
+
+func TestAllowedType(t *testing.T) {
+	html := []byte("<html><body><script>alert(1)</script></body></html>")
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16))
+	tests := []struct {
+		data   []byte
+		config UploadConfig
+		want   bool
+	}{
+		{[]byte("hello"), UploadConfig{AllowedTypes: []string{"text/plain"}}, true},
+		{html, UploadConfig{AllowedTypes: []string{"text/plain"}}, false},
+		{html, UploadConfig{AllowedTypes: []string{"text/plain"}, AllowParentTypes: true}, true},
+		{html, UploadConfig{AllowedTypes: []string{"text/html"}}, true},
+		{png, UploadConfig{AllowedTypes: []string{"image/*"}}, true},
+		//SVG 可以包含脚本，不通过通配符匹配
+		{svg, UploadConfig{AllowedTypes: []string{"image/*"}}, false},
+		{svg, UploadConfig{AllowedTypes: []string{"image/*"}, AllowParentTypes: true}, false},
+		{svg, UploadConfig{AllowedTypes: []string{"image/svg+xml"}}, true},
+		{svg, UploadConfig{}, true},
+	}
+	for _, tt := range tests {
+		mtype := mimetype.Detect(tt.data)
+		if got := allowedType(mtype, tt.config); got != tt.want {
+			t.Errorf("%s with %v (parents %v) = %v, want %v", mtype, tt.config.AllowedTypes, tt.config.AllowParentTypes, got, tt.want)
+		}
+	}
+}
+
+func TestSaveUploadedFilesFormFields(t *testing.T) {
+	_, name, err := runSaveUploadedFiles(t, UploadConfig{Dir: t.TempDir()}, uploadPart{"name", "", "msgo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "msgo" {
+		t.Fatalf("GetPostForm after SaveUploadedFiles = %q", name)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a.txt", "a.txt"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\windows\system.ini`, "system.ini"},
+		{".env", "env"},
+		{"...", "file"},
+		{"", "file"},
+		{"a\x00b\nc.txt", "abc.txt"},
+		{`a<b>:"|?*.txt`, "ab.txt"},
+		{"  name.txt. ", "name.txt"},
+		{"报告.pdf", "报告.pdf"},
+		{"bad\xffname.txt", "badname.txt"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("报", 100) + ".txt"
+	got := SanitizeFilename(long)
+	if len(got) > maxFilenameLen || !strings.HasSuffix(got, ".txt") || !strings.HasPrefix(got, "报") {
+		t.Fatalf("long filename sanitized to %d bytes: %q", len(got), got)
+	}
+	if strings.ContainsRune(got, '�') {
+		t.Fatal("multi-byte character was split")
+	}
+}
@@ -1,6 +1,8 @@
 package msgo
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -31,3 +33,76 @@ func StringToBytes(s string) []byte {
 		}{s, len(s)},
 	))
 }
+
+// filterFlags 去掉 Content-Type 中 ; 之后的参数
+func filterFlags(content string) string {
+	for i, char := range content {
+		if char == ' ' || char == ';' {
+			return content[:i]
+		}
+	}
+	return content
+}
+
+// acceptValue Accept 类请求头中的一项，例如 zh-CN;q=0.8，q=0 表示明确不接受
+type acceptValue struct {
+	value string
+	q     float64
+}
+
+// parseAccept 解析 Accept、Accept-Language 等请求头，按照 q 值从高到低排序，q=0 的项会被丢弃
+func parseAccept(header string) []string {
+	values := parseAcceptValues(header)
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v.q > 0 {
+			result = append(result, v.value)
+		}
+	}
+	return result
+}
+
+// parseAcceptValues 和 parseAccept 相同，但是保留 q=0 的项，匹配时用于排除，见 RFC 7231 5.3.2
+func parseAcceptValues(header string) []acceptValue {
+	values := make([]acceptValue, 0)
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q < 0 {
+			q = 0
+		}
+		values = append(values, acceptValue{value: value, q: q})
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].q > values[j].q
+	})
+	return values
+}
+
+// parseAcceptLanguage 解析 Accept-Language，zh-CN 会同时返回 zh_cn 和 zh，方便查找翻译
+func parseAcceptLanguage(header string) []string {
+	locales := make([]string, 0)
+	for _, lang := range parseAccept(header) {
+		if lang == "*" {
+			continue
+		}
+		lang = strings.ToLower(strings.ReplaceAll(lang, "-", "_"))
+		locales = append(locales, lang)
+		if base, _, ok := strings.Cut(lang, "_"); ok {
+			locales = append(locales, base)
+		}
+	}
+	return locales
+}
@@ -1,21 +1,76 @@
 package msgo
 
 import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
 	"reflect"
+	"strings"
 	"sync"
 )
 
 type StructValidator interface {
 	ValidateStruct(any) error //结构体验证
 	Engine() any
+	// Translate 把校验错误翻译成 locales 中第一个支持的语言，不是校验错误时返回 nil
+	Translate(err error, locales ...string) FieldErrors
+
+	// RegisterValidation 注册自定义的校验规则，在 validate tag 中通过 tag 使用
+	RegisterValidation(tag string, fn ValidationFunc, callValidationEvenIfNull ...bool) error
+	// RegisterCrossFieldValidation 注册跨字段的校验规则，tag 的参数为同一结构体中另一个字段的名字，例如 validate:"after=StartTime"
+	RegisterCrossFieldValidation(tag string, fn CrossFieldFunc) error
+	// RegisterStructValidation 注册结构体级别的校验，types 为需要校验的结构体类型
+	RegisterStructValidation(fn StructLevelFunc, types ...any)
+	// RegisterAlias 注册校验规则的别名，例如 RegisterAlias("mobile", "required,len=11,numeric")
+	RegisterAlias(alias, tags string)
+	// RegisterTranslation 注册自定义规则在 locale 下的错误信息，{0} 为字段名，{1} 为参数
+	RegisterTranslation(tag, locale, message string) error
 }
 
-var Validator StructValidator = &defaultValidator{}
+// Validator 全局默认的校验器，Engine 没有单独设置校验器时使用
+var Validator StructValidator = NewValidator()
+
+type (
+	FieldLevel      = validator.FieldLevel
+	StructLevel     = validator.StructLevel
+	ValidationFunc  = validator.Func
+	StructLevelFunc = validator.StructLevelFunc
+	// CrossFieldFunc field 为当前字段的值，other 为参数指定的字段的值
+	CrossFieldFunc func(field, other reflect.Value) bool
+)
+
+// NewValidator 创建一个基于 go-playground/validator 的校验器
+func NewValidator() StructValidator {
+	return &defaultValidator{}
+}
+
+// FieldError 翻译后的字段校验错误，可以直接作为接口的响应返回
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type FieldErrors []FieldError
+
+func (fe FieldErrors) Error() string {
+	messages := make([]string, len(fe))
+	for i, e := range fe {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
 
 type defaultValidator struct {
 	one      sync.Once
 	validate *validator.Validate
+	uni      *ut.UniversalTranslator
 }
 
 func (d *defaultValidator) ValidateStruct(obj any) error {
@@ -32,8 +87,8 @@ func (d *defaultValidator) ValidateStruct(obj any) error {
 		count := value.Len()
 		validateRet := make(SliceValidationError, 0)
 		for i := 0; i < count; i++ {
-			if err := d.validateStruct(value.Index(i).Interface()); err != nil {
-				validateRet = append(validateRet, err)
+			if err := d.ValidateStruct(value.Index(i).Interface()); err != nil {
+				validateRet = append(validateRet, SliceElementError{Index: i, Err: err})
 			}
 		}
 		if len(validateRet) == 0 {
@@ -54,9 +109,120 @@ func (d *defaultValidator) validateStruct(obj any) error {
 	return d.validate.Struct(obj)
 }
 
+// Translate 找不到对应语言时使用英文，切片元素的错误字段为 [1].name 的形式
+func (d *defaultValidator) Translate(err error, locales ...string) FieldErrors {
+	var sliceErrors SliceValidationError
+	if errors.As(err, &sliceErrors) {
+		fieldErrors := make(FieldErrors, 0, len(sliceErrors))
+		for _, e := range sliceErrors {
+			for _, fe := range d.Translate(e.Err, locales...) {
+				fe.Field = indexPath(e.Index, fe.Field)
+				fieldErrors = append(fieldErrors, fe)
+			}
+		}
+		if len(fieldErrors) == 0 {
+			return nil
+		}
+		return fieldErrors
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+	d.lazyInit()
+	trans, _ := d.uni.FindTranslator(locales...)
+	fieldErrors := make(FieldErrors, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   fieldPath(fe.Namespace()),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+		})
+	}
+	return fieldErrors
+}
+
+func (d *defaultValidator) RegisterValidation(tag string, fn ValidationFunc, callValidationEvenIfNull ...bool) error {
+	d.lazyInit()
+	return d.validate.RegisterValidation(tag, fn, callValidationEvenIfNull...)
+}
+
+func (d *defaultValidator) RegisterCrossFieldValidation(tag string, fn CrossFieldFunc) error {
+	d.lazyInit()
+	return d.validate.RegisterValidation(tag, func(fl FieldLevel) bool {
+		other, _, _, ok := fl.GetStructFieldOK2()
+		if !ok {
+			return false
+		}
+		return fn(fl.Field(), other)
+	})
+}
+
+func (d *defaultValidator) RegisterStructValidation(fn StructLevelFunc, types ...any) {
+	d.lazyInit()
+	d.validate.RegisterStructValidation(fn, types...)
+}
+
+func (d *defaultValidator) RegisterAlias(alias, tags string) {
+	d.lazyInit()
+	d.validate.RegisterAlias(alias, tags)
+}
+
+func (d *defaultValidator) RegisterTranslation(tag, locale, message string) error {
+	d.lazyInit()
+	trans, found := d.uni.GetTranslator(locale)
+	if !found {
+		return fmt.Errorf("locale %s is not supported", locale)
+	}
+	return d.validate.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+		return ut.Add(tag, message, true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, err := ut.T(tag, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return t
+	})
+}
+
 // 多线程环境下，只有第一次调用时才会执行其中的代码,并初始化d.validate，并且以后的调用将不再执行初始化过程
 func (d *defaultValidator) lazyInit() {
 	d.one.Do(func() {
 		d.validate = validator.New()
+		//错误信息中的字段名使用 json tag
+		d.validate.RegisterTagNameFunc(jsonTagName)
+		enLocale := en.New()
+		d.uni = ut.New(enLocale, enLocale, zh.New())
+		enTrans, _ := d.uni.GetTranslator("en")
+		zhTrans, _ := d.uni.GetTranslator("zh")
+		_ = enTranslations.RegisterDefaultTranslations(d.validate, enTrans)
+		_ = zhTranslations.RegisterDefaultTranslations(d.validate, zhTrans)
 	})
 }
+
+func jsonTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fieldPath 去掉 Namespace 中最外层的结构体名，User.address.city 转换为 address.city
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+func indexPath(index int, path string) string {
+	if path == "" || strings.HasPrefix(path, "[") {
+		return fmt.Sprintf("[%d]%s", index, path)
+	}
+	return fmt.Sprintf("[%d].%s", index, path)
+}
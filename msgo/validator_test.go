@@ -0,0 +1,293 @@
+package msgo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name    string          `json:"name" validate:"required"`
+	Age     int             `json:"age,omitempty" validate:"gte=18"`
+	Email   string          `validate:"omitempty,email"`
+	Address validateAddress `json:"address"`
+	Ignored string          `json:"-" validate:"max=1"`
+}
+
+func TestValidatorTranslate(t *testing.T) {
+	v := NewValidator()
+	err := v.ValidateStruct(&validateUser{Age: 1, Email: "x", Ignored: "ab"})
+	if err == nil {
+		t.Fatal("invalid struct passed validation")
+	}
+	tests := []struct {
+		locales []string
+		want    FieldErrors
+	}{
+		{[]string{"en"}, FieldErrors{
+			{Field: "name", Tag: "required", Message: "name is a required field"},
+			{Field: "age", Tag: "gte", Param: "18", Message: "age must be 18 or greater"},
+			{Field: "Email", Tag: "email", Message: "Email must be a valid email address"},
+			{Field: "address.city", Tag: "required", Message: "city is a required field"},
+			{Field: "Ignored", Tag: "max", Param: "1", Message: "Ignored must be a maximum of 1 character in length"},
+		}},
+		{[]string{"zh"}, FieldErrors{
+			{Field: "name", Tag: "required", Message: "name为必填字段"},
+			{Field: "age", Tag: "gte", Param: "18", Message: "age必须大于或等于18"},
+			{Field: "Email", Tag: "email", Message: "Email必须是一个有效的邮箱"},
+			{Field: "address.city", Tag: "required", Message: "city为必填字段"},
+			{Field: "Ignored", Tag: "max", Param: "1", Message: "Ignored长度不能超过1个字符"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := v.Translate(err, tt.locales...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v:\ngot  %+v\nwant %+v", tt.locales, got, tt.want)
+		}
+	}
+	//不支持的语言使用英文，不是校验错误时返回 nil
+	if got := v.Translate(err, "fr"); got[0].Message != "name is a required field" {
+		t.Errorf("fallback = %+v", got)
+	}
+	if got := v.Translate(http.ErrBodyNotAllowed, "en"); got != nil {
+		t.Errorf("non-validation error = %+v", got)
+	}
+}
+
+func TestContextTranslateError(t *testing.T) {
+	engine := NewEngine()
+	engine.Group("t").Post("/user", func(ctx *Context) {
+		var user validateUser
+		if ctx.BindJSON(&user) == nil {
+			_ = ctx.String(http.StatusOK, "ok")
+		}
+	})
+	tests := []struct {
+		acceptLanguage string
+		message        string
+	}{
+		{"", "name is a required field"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "name为必填字段"},
+		{"fr-FR,en;q=0.5,zh;q=0.8", "name为必填字段"},
+		{"en-US,zh;q=0.5", "name is a required field"},
+		{"fr", "name is a required field"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/t/user", strings.NewReader(`{"age":20,"address":{"city":"a"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var resp struct {
+			Code int         `json:"code"`
+			Msg  FieldErrors `json:"msg"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%q: %v %s", tt.acceptLanguage, err, w.Body.String())
+		}
+		want := FieldErrors{{Field: "name", Tag: "required", Message: tt.message}}
+		if w.Code != http.StatusBadRequest || resp.Code != http.StatusBadRequest || !reflect.DeepEqual(resp.Msg, want) {
+			t.Errorf("%q: %d %s", tt.acceptLanguage, w.Code, w.Body.String())
+		}
+	}
+}
+
+type validateEvent struct {
+	Code  string    `json:"code" validate:"code"`
+	Phone string    `json:"phone" validate:"omitempty,mobile"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end" validate:"after=Start"`
+}
+
+func TestValidatorRegister(t *testing.T) {
+	v := NewValidator()
+	if err := v.RegisterValidation("code", func(fl FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "ms-")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.RegisterCrossFieldValidation("after", func(field, other reflect.Value) bool {
+		return field.Interface().(time.Time).After(other.Interface().(time.Time))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	v.RegisterAlias("mobile", "len=11,numeric")
+	v.RegisterStructValidation(func(sl StructLevel) {
+		event := sl.Current().Interface().(validateEvent)
+		if event.Code == "ms-reserved" {
+			sl.ReportError(event.Code, "code", "Code", "reserved", "")
+		}
+	}, validateEvent{})
+	for locale, message := range map[string]string{"en": "{0} must start with ms-", "zh": "{0}必须以ms-开头"} {
+		if err := v.RegisterTranslation("code", locale, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := v.RegisterTranslation("code", "fr", "{0}"); err == nil {
+		t.Fatal("unsupported locale should fail")
+	}
+
+	now := time.Now()
+	valid := validateEvent{Code: "ms-1", Phone: "13800000000", Start: now, End: now.Add(time.Hour)}
+	if err := v.ValidateStruct(&valid); err != nil {
+		t.Fatalf("valid event: %v", err)
+	}
+	tests := []struct {
+		event validateEvent
+		want  FieldErrors
+	}{
+		{validateEvent{Code: "x", Start: now, End: now.Add(time.Hour)}, FieldErrors{{Field: "code", Tag: "code", Message: "code必须以ms-开头"}}},
+		{validateEvent{Code: "ms-1", Phone: "138", Start: now, End: now.Add(time.Hour)}, FieldErrors{{Field: "phone", Tag: "mobile", Param: "11", Message: "phone长度必须是11个字符"}}},
+		{validateEvent{Code: "ms-1", Start: now, End: now}, FieldErrors{{Field: "end", Tag: "after", Param: "Start"}}},
+		{validateEvent{Code: "ms-reserved", Start: now, End: now.Add(time.Hour)}, FieldErrors{{Field: "code", Tag: "reserved"}}},
+	}
+	for _, tt := range tests {
+		got := v.Translate(v.ValidateStruct(&tt.event), "zh")
+		//没有翻译的规则只比较字段和规则名
+		for i := range got {
+			if i < len(tt.want) && tt.want[i].Message == "" {
+				got[i].Message = ""
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v:\ngot  %+v\nwant %+v", tt.event, []FieldError(got), []FieldError(tt.want))
+		}
+	}
+	if got := v.Translate(v.ValidateStruct(&validateEvent{Code: "x", Start: now, End: now.Add(time.Hour)}), "en"); got[0].Message != "code must start with ms-" {
+		t.Errorf("en translation = %+v", got)
+	}
+}
+
+func TestEngineSetValidator(t *testing.T) {
+	strict := NewValidator()
+	if err := strict.RegisterValidation("code", func(fl FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "ms-")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	loose := NewValidator()
+	if err := loose.RegisterValidation("code", func(fl FieldLevel) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+	newEngine := func(v StructValidator) *Engine {
+		engine := NewEngine()
+		engine.SetValidator(v)
+		engine.Group("t").Post("/code", func(ctx *Context) {
+			var body struct {
+				Code string `json:"code" validate:"code"`
+			}
+			if ctx.BindJSON(&body) == nil {
+				_ = ctx.String(http.StatusOK, body.Code)
+			}
+		})
+		return engine
+	}
+	strictEngine, looseEngine := newEngine(strict), newEngine(loose)
+	if strictEngine.Validator() != strict || looseEngine.Validator() != loose || NewEngine().Validator() != Validator {
+		t.Fatal("engine validators are shared")
+	}
+	for engine, code := range map[*Engine]int{strictEngine: http.StatusBadRequest, looseEngine: http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/t/code", strings.NewReader(`{"code":"x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("%d %s, want %d", w.Code, w.Body.String(), code)
+		}
+	}
+}
+
+func TestValidatorSlice(t *testing.T) {
+	v := NewValidator()
+	users := []validateUser{
+		{Name: "a", Age: 18, Address: validateAddress{City: "a"}},
+		{Age: 18, Address: validateAddress{City: "b"}},
+		{Name: "c", Age: 18},
+	}
+	err := v.ValidateStruct(&users)
+	var sliceErr SliceValidationError
+	if !errors.As(err, &sliceErr) || len(sliceErr) != 2 || sliceErr[0].Index != 1 || sliceErr[1].Index != 2 {
+		t.Fatalf("err = %v", err)
+	}
+	want := FieldErrors{
+		{Field: "[1].name", Tag: "required", Message: "name is a required field"},
+		{Field: "[2].address.city", Tag: "required", Message: "city is a required field"},
+	}
+	if got := v.Translate(err, "en"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", []FieldError(got), []FieldError(want))
+	}
+
+	//嵌套的切片使用 [i][j] 的形式
+	nested := [][]validateAddress{{{City: "a"}}, {{City: "b"}, {}}}
+	got := v.Translate(v.ValidateStruct(nested), "en")
+	if len(got) != 1 || got[0].Field != "[1][1].city" {
+		t.Fatalf("nested = %+v", []FieldError(got))
+	}
+	if err := v.ValidateStruct(users[:1]); err != nil {
+		t.Fatalf("valid slice: %v", err)
+	}
+}
+
+func TestBindValidationErrorShape(t *testing.T) {
+	type params struct {
+		ID   int    `uri:"id" form:"id" header:"X-Id" json:"id" validate:"gte=10"`
+		Name string `uri:"name" form:"name" header:"X-Name" json:"name" validate:"required"`
+	}
+	engine := NewEngine()
+	g := engine.Group("t")
+	bind := func(path string, fn func(*Context, *params) error) {
+		g.Post(path, func(ctx *Context) {
+			var p params
+			if fn(ctx, &p) == nil {
+				_ = ctx.String(http.StatusOK, "ok")
+			}
+		})
+	}
+	bind("/query", func(ctx *Context, p *params) error { return ctx.BindQuery(p) })
+	bind("/form", func(ctx *Context, p *params) error { return ctx.BindForm(p) })
+	bind("/header", func(ctx *Context, p *params) error { return ctx.BindHeader(p) })
+	bind("/json", func(ctx *Context, p *params) error { return ctx.BindJSON(p) })
+	bind("/uri/:id/*", func(ctx *Context, p *params) error { return ctx.BindUri(p) })
+
+	newRequest := func(path string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		switch path {
+		case "/t/form":
+			req = httptest.NewRequest(http.MethodPost, path, strings.NewReader("id=1&name="))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		case "/t/header":
+			req.Header.Set("X-Id", "1")
+		case "/t/json":
+			req = httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"id":1}`))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req
+	}
+	want := FieldErrors{
+		{Field: "id", Tag: "gte", Param: "10", Message: "id must be 10 or greater"},
+		{Field: "name", Tag: "required", Message: "name is a required field"},
+	}
+	for _, path := range []string{"/t/query?id=1", "/t/form", "/t/header", "/t/json", "/t/uri/1/x"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, newRequest(path))
+		var resp struct {
+			Code int         `json:"code"`
+			Msg  FieldErrors `json:"msg"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s: %v %s", path, err, w.Body.String())
+			continue
+		}
+		if w.Code != http.StatusBadRequest || resp.Code != http.StatusBadRequest || !reflect.DeepEqual(resp.Msg, want) {
+			t.Errorf("%s: %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package msgo
+
+import (
+	"github.com/mis403/msgo/websocket"
+	"log"
+	"net/http"
+)
+
+// WebSocketHandler 处理升级后的 WebSocket 连接，handler 返回后连接会被关闭
+type WebSocketHandler func(ctx *Context, conn *websocket.Conn)
+
+// WebSocket 注册 WebSocket 路由，握手在中间件执行之后进行，鉴权等中间件可以直接拒绝请求
+func (r *routerGroup) WebSocket(name string, handler WebSocketHandler, middlewareFunc ...MiddlewareFunc) {
+	r.handle(name, http.MethodGet, func(ctx *Context) {
+		conn, err := ctx.engine.webSocketUpgrader().Upgrade(ctx.W, ctx.R, nil)
+		if err != nil {
+			//握手失败时 Upgrade 已经写出了错误响应
+			log.Println(err)
+			return
+		}
+		defer conn.Close()
+		handler(ctx, conn)
+	}, middlewareFunc...)
+}
+
+func (e *Engine) webSocketUpgrader() *websocket.Upgrader {
+	if e.WebSocketUpgrader != nil {
+		return e.WebSocketUpgrader
+	}
+	return defaultUpgrader
+}
+
+var defaultUpgrader = &websocket.Upgrader{}
@@ -0,0 +1,510 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，和帧的 opcode 相同
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// 关闭连接的状态码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+// 控制帧的 payload 最大为 125 字节
+const maxControlFramePayloadSize = 125
+
+// DefaultMaxMessageSize 默认的单条消息最大字节数
+const DefaultMaxMessageSize = 1 << 20
+
+// readChunkSize payload 超过这个大小时按块读取，内存随实际收到的数据增长，而不是按照帧头中的长度一次分配
+const readChunkSize = 64 << 10
+
+// ErrCloseSent 已经发送了关闭帧，不能再发送数据
+var ErrCloseSent = errors.New("websocket: close sent")
+
+// CloseError 连接被关闭，Code 为关闭的状态码
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError 判断 err 是否为 codes 中的某个状态码的关闭错误
+func IsCloseError(err error, codes ...int) bool {
+	var closeError *CloseError
+	if !errors.As(err, &closeError) {
+		return false
+	}
+	for _, code := range codes {
+		if closeError.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn WebSocket 连接。同一时间只能有一个 goroutine 读，写操作可以并发调用
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	subprotocol  string
+	compression  bool
+	readLimit    int64
+	fragmentSize int
+	readErr      error
+
+	writeMu       sync.Mutex
+	closeSent     bool
+	writeDeadline time.Time
+
+	pingHandler  func(appData string) error
+	pongHandler  func(appData string) error
+	closeHandler func(code int, text string) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader) *Conn {
+	c := &Conn{conn: conn, br: br, readLimit: DefaultMaxMessageSize}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	c.SetCloseHandler(nil)
+	return c
+}
+
+// Subprotocol 握手时协商的子协议
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr 客户端地址
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit 设置单条消息的最大字节数，超过时以 1009 关闭连接，小于等于 0 表示不限制
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+// SetPingHandler 设置收到 ping 时的处理，为 nil 时回复 pong
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(appData string) error {
+			err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+			if errors.Is(err, ErrCloseSent) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.pingHandler = h
+}
+
+// SetPongHandler 设置收到 pong 时的处理，为 nil 时忽略
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.pongHandler = h
+}
+
+// SetCloseHandler 设置收到关闭帧时的处理，为 nil 时回复相同状态码的关闭帧
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			message := FormatCloseMessage(code, "")
+			err := c.WriteControl(CloseMessage, message, time.Now().Add(time.Second))
+			if errors.Is(err, ErrCloseSent) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.closeHandler = h
+}
+
+// ReadMessage 读取一条完整的消息，分片的消息会被合并，压缩的消息会被解压。
+// 读取过程中收到的控制帧交给对应的 handler 处理，收到关闭帧时返回 *CloseError
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, data, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return messageType, data, err
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	messageType := 0
+	compressed := false
+	var data []byte
+	for {
+		//分片的消息总长度也受 readLimit 的限制，剩余的额度传给 readFrame，在读取 payload 之前检查
+		limit := int64(-1)
+		if c.readLimit > 0 {
+			limit = c.readLimit - int64(len(data))
+		}
+		f, err := c.readFrame(limit)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case PingMessage, PongMessage, CloseMessage:
+			if err := c.handleControl(f); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = f.opcode
+			compressed = f.rsv1
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(CloseProtocolError, "RSV1 set on continuation frame")
+			}
+		}
+		data = append(data, f.payload...)
+		if f.fin {
+			break
+		}
+	}
+	if compressed {
+		var err error
+		if data, err = decompress(data, c.readLimit); err != nil {
+			if errors.Is(err, errMessageTooBig) {
+				return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+			}
+			return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid compressed data")
+		}
+	}
+	if messageType == TextMessage && !utf8.Valid(data) {
+		return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in text message")
+	}
+	return messageType, data, nil
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// readFrame 读取一个帧，见 RFC 6455 5.2。limit 大于等于 0 时数据帧的 payload 超过 limit 以 1009 关闭连接
+func (c *Conn) readFrame(limit int64) (*frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return nil, c.readError(err)
+	}
+	f := &frame{
+		fin:    header[0]&0x80 != 0,
+		rsv1:   header[0]&0x40 != 0,
+		opcode: int(header[0] & 0x0f),
+	}
+	if header[0]&0x30 != 0 {
+		return nil, c.fail(CloseProtocolError, "unexpected reserved bits")
+	}
+	isControl := f.opcode >= CloseMessage
+	switch f.opcode {
+	case continuationFrame, TextMessage, BinaryMessage, CloseMessage, PingMessage, PongMessage:
+	default:
+		return nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", f.opcode))
+	}
+	if f.rsv1 && (!c.compression || isControl) {
+		return nil, c.fail(CloseProtocolError, "unexpected RSV1 bit")
+	}
+	//客户端发送的帧必须使用掩码
+	if header[1]&0x80 == 0 {
+		return nil, c.fail(CloseProtocolError, "client frame is not masked")
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return nil, c.readError(err)
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return nil, c.readError(err)
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+		if length < 0 {
+			return nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+	if isControl && (length > maxControlFramePayloadSize || !f.fin) {
+		return nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if !isControl && limit >= 0 && length > limit {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var maskKey [4]byte
+	if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+		return nil, c.readError(err)
+	}
+	payload, err := c.readPayload(length)
+	if err != nil {
+		return nil, c.readError(err)
+	}
+	for i := range payload {
+		payload[i] ^= maskKey[i%4]
+	}
+	f.payload = payload
+	return f, nil
+}
+
+// readPayload 较小的 payload 直接分配，较大的按块读取，避免客户端声明很大的长度却不发送数据时占用内存
+func (c *Conn) readPayload(length int64) ([]byte, error) {
+	if length <= readChunkSize {
+		payload := make([]byte, length)
+		_, err := io.ReadFull(c.br, payload)
+		return payload, err
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, c.br, length)
+	if err == io.EOF && n < length {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+func (c *Conn) handleControl(f *frame) error {
+	switch f.opcode {
+	case PingMessage:
+		return c.pingHandler(string(f.payload))
+	case PongMessage:
+		return c.pongHandler(string(f.payload))
+	}
+	code := CloseNoStatusReceived
+	text := ""
+	if len(f.payload) == 1 {
+		return c.fail(CloseProtocolError, "invalid close payload")
+	}
+	if len(f.payload) >= 2 {
+		code = int(binary.BigEndian.Uint16(f.payload))
+		if !isValidReceivedCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		text = string(f.payload[2:])
+		if !utf8.ValidString(text) {
+			return c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in close frame")
+		}
+	}
+	if err := c.closeHandler(code, text); err != nil {
+		return err
+	}
+	return &CloseError{Code: code, Text: text}
+}
+
+// fail 协议错误时发送关闭帧，返回对应状态码的 CloseError
+func (c *Conn) fail(code int, text string) error {
+	_ = c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	return &CloseError{Code: code, Text: text}
+}
+
+// readError 连接意外断开时返回 1006
+func (c *Conn) readError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	}
+	return err
+}
+
+// WriteMessage 发送一条文本或二进制消息，控制消息会转交给 WriteControl
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		return c.WriteControl(messageType, data, time.Time{})
+	default:
+		return fmt.Errorf("websocket: unknown message type %d", messageType)
+	}
+	rsv1 := false
+	if c.compression {
+		compressed, err := compress(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		rsv1 = true
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	c.conn.SetWriteDeadline(c.writeDeadline)
+
+	//按照 fragmentSize 分片发送，只有第一个分片带有 opcode 和 RSV1
+	opcode := messageType
+	for {
+		chunk := data
+		if c.fragmentSize > 0 && len(chunk) > c.fragmentSize {
+			chunk = chunk[:c.fragmentSize]
+		}
+		data = data[len(chunk):]
+		if err := c.writeFrame(len(data) == 0, rsv1, opcode, chunk); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		opcode = continuationFrame
+		rsv1 = false
+	}
+}
+
+// WriteJSON 将 v 编码为 JSON 后作为文本消息发送
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// ReadJSON 读取一条消息并解析到 v
+func (c *Conn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteControl 发送控制帧，deadline 为零值时不设置超时。发送关闭帧之后不能再发送其他数据
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: invalid control message type %d", messageType)
+	}
+	if len(data) > maxControlFramePayloadSize {
+		return errors.New("websocket: control frame payload too large")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	c.conn.SetWriteDeadline(deadline)
+	return c.writeFrame(true, false, messageType, data)
+}
+
+// writeFrame 服务端发送的帧不使用掩码
+func (c *Conn) writeFrame(fin, rsv1 bool, opcode int, payload []byte) error {
+	header := make([]byte, 2, 10+len(payload))
+	header[0] = byte(opcode)
+	if fin {
+		header[0] |= 0x80
+	}
+	if rsv1 {
+		header[0] |= 0x40
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// Close 发送正常关闭的关闭帧并关闭底层连接
+func (c *Conn) Close() error {
+	return c.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode 使用指定的状态码关闭连接
+func (c *Conn) CloseWithCode(code int, text string) error {
+	_ = c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	return c.conn.Close()
+}
+
+// FormatCloseMessage 构造关闭帧的 payload，CloseNoStatusReceived 表示不带状态码
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	if len(text) > maxControlFramePayloadSize-2 {
+		text = text[:maxControlFramePayloadSize-2]
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// isValidReceivedCloseCode 1005、1006、1015 不能出现在关闭帧中
+func isValidReceivedCloseCode(code int) bool {
+	switch {
+	case code >= CloseNormalClosure && code <= CloseUnsupportedData,
+		code >= CloseInvalidFramePayloadData && code <= CloseInternalServerErr,
+		code == 1012, code == 1013, code == 1014:
+		return true
+	}
+	return code >= 3000 && code <= 4999
+}
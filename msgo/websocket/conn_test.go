@@ -0,0 +1,261 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestConn 返回服务端的 Conn 和模拟客户端的一端
+func newTestConn(t *testing.T) (*Conn, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return newConn(server, bufio.NewReader(server)), client
+}
+
+// clientFrame 构造客户端发送的带掩码的帧，length 小于 0 时使用 payload 的实际长度
+func clientFrame(fin bool, rsv1 bool, opcode int, payload []byte, length int64) []byte {
+	var b bytes.Buffer
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	if rsv1 {
+		first |= 0x40
+	}
+	b.WriteByte(first)
+	if length < 0 {
+		length = int64(len(payload))
+	}
+	switch {
+	case length <= 125:
+		b.WriteByte(0x80 | byte(length))
+	case length <= 0xffff:
+		b.WriteByte(0x80 | 126)
+		_ = binary.Write(&b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(0x80 | 127)
+		_ = binary.Write(&b, binary.BigEndian, uint64(length))
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	b.Write(mask[:])
+	for i, c := range payload {
+		b.WriteByte(c ^ mask[i%4])
+	}
+	return b.Bytes()
+}
+
+// readServerFrame 读取服务端发送的一个不带掩码的帧
+func readServerFrame(r io.Reader) (opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		length = int(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		length = int(binary.BigEndian.Uint64(b[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return int(header[0] & 0x0f), payload, nil
+}
+
+// expectClose 在后台写入 data，检查 ReadMessage 返回 code 对应的 CloseError，并且服务端发送了相同状态码的关闭帧
+func expectClose(t *testing.T, data []byte, code int, setup func(c *Conn)) {
+	t.Helper()
+	conn, client := newTestConn(t)
+	if setup != nil {
+		setup(conn)
+	}
+	go func() {
+		_, _ = client.Write(data)
+	}()
+	closeCode := make(chan int, 1)
+	go func() {
+		for {
+			opcode, payload, err := readServerFrame(client)
+			if err != nil {
+				return
+			}
+			if opcode == CloseMessage && len(payload) >= 2 {
+				closeCode <- int(binary.BigEndian.Uint16(payload))
+				return
+			}
+		}
+	}()
+	_, _, err := conn.ReadMessage()
+	if !IsCloseError(err, code) {
+		t.Fatalf("ReadMessage error = %v, want close %d", err, code)
+	}
+	select {
+	case got := <-closeCode:
+		if got != code {
+			t.Fatalf("close frame code = %d, want %d", got, code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no close frame sent")
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	conn, client := newTestConn(t)
+	go func() {
+		_, _ = client.Write(clientFrame(true, false, TextMessage, []byte("hello"), -1))
+	}()
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != TextMessage || string(data) != "hello" {
+		t.Fatalf("got %d %q", messageType, data)
+	}
+}
+
+func TestReadMessageFragmented(t *testing.T) {
+	conn, client := newTestConn(t)
+	go func() {
+		_, _ = client.Write(clientFrame(false, false, BinaryMessage, []byte("hel"), -1))
+		//分片之间可以插入控制帧
+		_, _ = client.Write(clientFrame(true, false, PongMessage, nil, -1))
+		_, _ = client.Write(clientFrame(true, false, continuationFrame, []byte("lo"), -1))
+	}()
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != BinaryMessage || string(data) != "hello" {
+		t.Fatalf("got %d %q", messageType, data)
+	}
+}
+
+func TestReadMessageLargePayload(t *testing.T) {
+	conn, client := newTestConn(t)
+	payload := bytes.Repeat([]byte("a"), readChunkSize*3+7)
+	go func() {
+		_, _ = client.Write(clientFrame(true, false, BinaryMessage, payload, -1))
+	}()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("got %d bytes, want %d", len(data), len(payload))
+	}
+}
+
+func TestPingHandler(t *testing.T) {
+	conn, client := newTestConn(t)
+	go func() {
+		_, _ = client.Write(clientFrame(true, false, PingMessage, []byte("p"), -1))
+	}()
+	go func() {
+		_, _, _ = conn.ReadMessage()
+	}()
+	opcode, payload, err := readServerFrame(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opcode != PongMessage || string(payload) != "p" {
+		t.Fatalf("got opcode %d payload %q", opcode, payload)
+	}
+}
+
+func TestReadMessageProtocolErrors(t *testing.T) {
+	unmasked := clientFrame(true, false, TextMessage, []byte("hi"), -1)
+	unmasked[1] &^= 0x80
+	tests := []struct {
+		name string
+		data []byte
+		code int
+	}{
+		{"unmasked", unmasked, CloseProtocolError},
+		{"reserved bits", clientFrame(true, false, TextMessage|0x20, nil, -1), CloseProtocolError},
+		{"unknown opcode", clientFrame(true, false, 3, nil, -1), CloseProtocolError},
+		{"rsv1 without compression", clientFrame(true, true, TextMessage, nil, -1), CloseProtocolError},
+		{"fragmented control frame", clientFrame(false, false, PingMessage, nil, -1), CloseProtocolError},
+		{"control frame too long", clientFrame(true, false, PingMessage, make([]byte, 126), -1), CloseProtocolError},
+		{"unexpected continuation", clientFrame(true, false, continuationFrame, []byte("x"), -1), CloseProtocolError},
+		{"invalid utf-8", clientFrame(true, false, TextMessage, []byte{0xff, 0xfe}, -1), CloseInvalidFramePayloadData},
+		{"invalid close code", clientFrame(true, false, CloseMessage, []byte{0x03, 0xed}, -1), CloseProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectClose(t, tt.data, tt.code, nil)
+		})
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	t.Run("default limit", func(t *testing.T) {
+		expectClose(t, clientFrame(true, false, BinaryMessage, nil, DefaultMaxMessageSize+1), CloseMessageTooBig, nil)
+	})
+	t.Run("huge length is rejected before allocating", func(t *testing.T) {
+		expectClose(t, clientFrame(true, false, BinaryMessage, nil, 1<<62), CloseMessageTooBig, nil)
+	})
+	t.Run("fragments", func(t *testing.T) {
+		data := append(clientFrame(false, false, BinaryMessage, make([]byte, 6), -1),
+			clientFrame(true, false, continuationFrame, make([]byte, 6), -1)...)
+		expectClose(t, data, CloseMessageTooBig, func(c *Conn) { c.SetReadLimit(10) })
+	})
+	t.Run("deflate bomb", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.BestCompression)
+		_, _ = w.Write(make([]byte, 1<<20))
+		_ = w.Flush()
+		compressed := buf.Bytes()[:buf.Len()-4]
+		expectClose(t, clientFrame(true, true, BinaryMessage, compressed, -1), CloseMessageTooBig, func(c *Conn) {
+			c.compression = true
+			c.SetReadLimit(1 << 16)
+		})
+	})
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("msgo websocket "), 100)
+	compressed, err := compress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := decompress(compressed, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatal("decompressed data does not match")
+	}
+	if _, err := decompress(compressed, int64(len(data)-1)); err != errMessageTooBig {
+		t.Fatalf("err = %v, want errMessageTooBig", err)
+	}
+}
+
+func TestFormatCloseMessage(t *testing.T) {
+	if got := FormatCloseMessage(CloseNoStatusReceived, "x"); len(got) != 0 {
+		t.Fatalf("got %v, want empty payload", got)
+	}
+	got := FormatCloseMessage(CloseNormalClosure, string(bytes.Repeat([]byte("a"), 200)))
+	if len(got) != maxControlFramePayloadSize || binary.BigEndian.Uint16(got) != CloseNormalClosure {
+		t.Fatalf("got %d bytes", len(got))
+	}
+}
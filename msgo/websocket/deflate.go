@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"strings"
+)
+
+// 解压时追加在消息末尾的数据：被去掉的 0x00 0x00 0xff 0xff 以及一个空的最终块，见 RFC 7692 7.2.2
+const deflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+
+var errMessageTooBig = errors.New("websocket: message too big")
+
+// compress 使用 permessage-deflate 压缩消息，去掉结尾的 0x00 0x00 0xff 0xff。
+// 协商时使用了 no_context_takeover，每条消息都使用新的压缩器
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	b := buf.Bytes()
+	if len(b) >= 4 {
+		b = b[:len(b)-4]
+	}
+	return b, nil
+}
+
+// decompress 解压消息，limit 大于 0 时解压后的数据超过 limit 返回 errMessageTooBig
+func decompress(data []byte, limit int64) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader(deflateTail)))
+	defer r.Close()
+	var src io.Reader = r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	out, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, errMessageTooBig
+	}
+	return out, nil
+}
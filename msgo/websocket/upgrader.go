@@ -0,0 +1,215 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6455 中用于计算 Sec-WebSocket-Accept 的固定字符串
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError 握手失败，Upgrade 已经向客户端返回了对应的错误响应
+type HandshakeError struct {
+	message string
+}
+
+func (e HandshakeError) Error() string {
+	return e.message
+}
+
+// Upgrader 将 HTTP 连接升级为 WebSocket 连接
+type Upgrader struct {
+	// HandshakeTimeout 握手的超时时间，0 表示不限制
+	HandshakeTimeout time.Duration
+	// MaxMessageSize 单条消息（合并分片、解压之后）的最大字节数，超过时以 1009 关闭连接，
+	// 0 时使用 DefaultMaxMessageSize，小于 0 表示不限制
+	MaxMessageSize int64
+	// WriteFragmentSize 发送消息时每个分片的最大字节数，0 表示不分片
+	WriteFragmentSize int
+	// Subprotocols 服务端支持的子协议，按照优先级排列
+	Subprotocols []string
+	// CheckOrigin 校验 Origin 请求头，为空时只允许同源的请求
+	CheckOrigin func(r *http.Request) bool
+	// EnableCompression 是否支持 permessage-deflate 压缩，需要客户端同时支持
+	EnableCompression bool
+}
+
+// Upgrade 完成握手并返回 WebSocket 连接，responseHeader 为额外的响应头。
+// 握手失败时会向客户端返回错误响应，调用方不需要再写响应
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return u.returnError(w, http.StatusMethodNotAllowed, "websocket: request method is not GET")
+	}
+	if !tokenListContains(r.Header, "Connection", "upgrade") {
+		return u.returnError(w, http.StatusBadRequest, "websocket: 'upgrade' token not found in 'Connection' header")
+	}
+	if !tokenListContains(r.Header, "Upgrade", "websocket") {
+		return u.returnError(w, http.StatusBadRequest, "websocket: 'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return u.returnError(w, http.StatusUpgradeRequired, "websocket: unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return u.returnError(w, http.StatusForbidden, "websocket: request origin not allowed")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return u.returnError(w, http.StatusBadRequest, "websocket: invalid 'Sec-WebSocket-Key' header")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && negotiateDeflate(r.Header)
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return u.returnError(w, http.StatusInternalServerError, "websocket: response does not implement http.Hijacker")
+	}
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return u.returnError(w, http.StatusInternalServerError, err.Error())
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(computeAcceptKey(key))
+	b.WriteString("\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	for k, vs := range responseHeader {
+		if k == "Sec-Websocket-Protocol" || k == "Sec-Websocket-Extensions" {
+			continue
+		}
+		for _, v := range vs {
+			b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Time{})
+	}
+
+	//客户端可能在握手之后立即发送数据，这部分数据已经在 brw.Reader 的缓冲区中
+	conn := newConn(netConn, brw.Reader)
+	conn.subprotocol = subprotocol
+	conn.compression = compress
+	if u.MaxMessageSize != 0 {
+		conn.readLimit = u.MaxMessageSize
+	}
+	conn.fragmentSize = u.WriteFragmentSize
+	return conn, nil
+}
+
+func (u *Upgrader) returnError(w http.ResponseWriter, status int, reason string) (*Conn, error) {
+	err := HandshakeError{message: reason}
+	w.Header().Set("Sec-Websocket-Version", "13")
+	http.Error(w, http.StatusText(status), status)
+	return nil, err
+}
+
+// selectSubprotocol 按照服务端的优先级选择客户端也支持的子协议
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	clientProtocols := Subprotocols(r)
+	for _, serverProtocol := range u.Subprotocols {
+		for _, clientProtocol := range clientProtocols {
+			if clientProtocol == serverProtocol {
+				return clientProtocol
+			}
+		}
+	}
+	return ""
+}
+
+// Subprotocols 返回客户端在 Sec-WebSocket-Protocol 中请求的子协议
+func Subprotocols(r *http.Request) []string {
+	protocols := make([]string, 0)
+	for _, h := range r.Header.Values("Sec-Websocket-Protocol") {
+		for _, p := range strings.Split(h, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+// IsWebSocketUpgrade 判断请求是否为 WebSocket 握手请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return tokenListContains(r.Header, "Connection", "upgrade") &&
+		tokenListContains(r.Header, "Upgrade", "websocket")
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// checkSameOrigin 没有 Origin 请求头或者 Origin 的 host 和请求的 Host 相同时允许
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// tokenListContains 判断以逗号分隔的请求头中是否包含 token，不区分大小写
+func tokenListContains(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// negotiateDeflate 客户端提供了可以接受的 permessage-deflate 参数时返回 true。
+// 标准库的 flate 固定使用 32K 的窗口，客户端要求 server_max_window_bits 小于 15 时不能使用
+func negotiateDeflate(header http.Header) bool {
+	for _, v := range header.Values("Sec-Websocket-Extensions") {
+		for _, ext := range strings.Split(v, ",") {
+			params := strings.Split(ext, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+			ok := true
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.TrimSpace(name) == "server_max_window_bits" && strings.Trim(strings.TrimSpace(value), `"`) != "15" {
+					ok = false
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}